@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"hexgrid/grid"
+	"hexgrid/spec"
+)
+
+// emptyItem is the item choice that clears a cell
+const emptyItem = "(empty)"
+
+// describeCell returns a line about a cell's label, item and dice roll
+func describeCell(g *grid.HexGrid, cell *grid.HexCell) string {
+	text := "Hex " + g.Label(cell) + ": "
+	if cell.ItemType == nil {
+		return text + "empty"
+	}
+	text += cell.ItemType.Name
+	if result := cell.DiceResult; result != nil {
+		text += fmt.Sprintf(", dice %s = %d", result.Breakdown, result.Total)
+	}
+	return text
+}
+
+// cellEditor is an open hex editor dialog
+type cellEditor struct {
+	dialog     dialog.Dialog
+	info       *widget.Label
+	itemSelect *widget.Select
+	update     func()
+}
+
+// Refresh shows the cell's content again after it was changed from outside
+// the dialog, as by Undo and Redo
+func (e *cellEditor) Refresh() {
+	e.update()
+}
+
+// Hide closes the dialog
+func (e *cellEditor) Hide() {
+	e.dialog.Hide()
+}
+
+// showCellEditor opens a dialog that edits one cell of the previewed grid.
+// Every change goes through the preview's history so it can be undone, and
+// changed is called after each one.
+func showCellEditor(window fyne.Window, preview *mapPreview, cell *grid.HexCell, changed func()) *cellEditor {
+	g, history := preview.Grid(), preview.History()
+	rng := rand.New(rand.NewSource(grid.NewSeed()))
+
+	info := widget.NewLabel(describeCell(g, cell))
+	diceInput := widget.NewEntry()
+	diceInput.SetPlaceHolder("total")
+	var itemSelect *widget.Select
+	var setDiceBtn, rerollBtn *widget.Button
+
+	// update shows the cell's content after a change
+	update := func() {
+		info.SetText(describeCell(g, cell))
+		// Selecting the cell's own item isn't a change
+		if cell.ItemType != nil {
+			itemSelect.SetSelected(cell.ItemType.Name)
+		} else {
+			itemSelect.SetSelected(emptyItem)
+		}
+		if cell.ItemType != nil && cell.ItemType.Dice != "" {
+			setDiceBtn.Enable()
+			rerollBtn.Enable()
+			diceInput.Enable()
+		} else {
+			setDiceBtn.Disable()
+			rerollBtn.Disable()
+			diceInput.Disable()
+		}
+	}
+
+	// apply makes a change to the cell
+	apply := func(content grid.CellContent, err error) {
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		history.Set(cell, content)
+		preview.Redraw()
+		update()
+		changed()
+	}
+
+	options := []string{emptyItem}
+	items := make(map[string]*spec.ItemType, len(g.ItemTypes))
+	for _, itemType := range g.ItemTypes {
+		options = append(options, itemType.Name)
+		items[itemType.Name] = itemType
+	}
+	itemSelect = widget.NewSelect(options, nil)
+	itemSelect.OnChanged = func(selected string) {
+		if items[selected] == cell.ItemType {
+			return
+		}
+		apply(grid.ItemContent(items[selected], rng))
+	}
+
+	setDiceBtn = widget.NewButton("Set", func() {
+		total, err := parseInt(strings.TrimSpace(diceInput.Text))
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("dice total must be a number: %q", diceInput.Text), window)
+			return
+		}
+		apply(grid.DiceContent(cell.ItemType, total))
+	})
+	rerollBtn = widget.NewButton("Reroll Dice", func() {
+		apply(grid.ItemContent(cell.ItemType, rng))
+	})
+	clearBtn := widget.NewButton("Clear Hex", func() {
+		itemSelect.SetSelected(emptyItem)
+	})
+	update()
+
+	content := container.NewVBox(
+		info,
+		container.NewHBox(widget.NewLabel("Item:"), itemSelect, clearBtn),
+		container.NewHBox(widget.NewLabel("Dice:"), diceInput, setDiceBtn, rerollBtn),
+	)
+	editor := &cellEditor{
+		dialog:     dialog.NewCustom("Edit Hex "+g.Label(cell), "Close", content, window),
+		info:       info,
+		itemSelect: itemSelect,
+		update:     update,
+	}
+	editor.dialog.Show()
+	return editor
+}
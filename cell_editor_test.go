@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+
+	"hexgrid/dice"
+	"hexgrid/grid"
+)
+
+func TestDescribeCell(t *testing.T) {
+	g := previewGrid(t)
+	cell := g.Cells[0][2] // drawn in the fifth column in the staggered layout
+	if got := describeCell(g, cell); got != "Hex 0501: Sea" {
+		t.Errorf("Unexpected description %q", got)
+	}
+
+	var history grid.History
+	history.Set(cell, grid.CellContent{ItemType: cell.ItemType, DiceResult: &dice.Result{Total: 7, Breakdown: "2d6[3,4]"}})
+	if got := describeCell(g, cell); got != "Hex 0501: Sea, dice 2d6[3,4] = 7" {
+		t.Errorf("Unexpected description %q", got)
+	}
+	history.Set(cell, grid.CellContent{})
+	if got := describeCell(g, cell); got != "Hex 0501: empty" {
+		t.Errorf("Unexpected description %q", got)
+	}
+}
+
+func TestCellEditorRefresh(t *testing.T) {
+	test.NewApp()
+	window := test.NewWindow(nil)
+	defer window.Close()
+	preview := newMapPreview()
+	preview.SetGrid(previewGrid(t))
+	cell := preview.Grid().Cells[0][2]
+
+	changes := 0
+	editor := showCellEditor(window, preview, cell, func() { changes++ })
+	editor.itemSelect.SetSelected(emptyItem)
+	if cell.ItemType != nil || changes != 1 {
+		t.Fatalf("Expected the hex to be cleared once, got %v after %d changes", cell.ItemType, changes)
+	}
+
+	// Undo from the main window shows the hex as it was again
+	preview.History().Undo()
+	editor.Refresh()
+	if got := editor.itemSelect.Selected; got != "Sea" {
+		t.Errorf("Expected Sea to be selected after undo, got %q", got)
+	}
+	if got := editor.info.Text; got != "Hex 0501: Sea" {
+		t.Errorf("Unexpected description after undo %q", got)
+	}
+	if changes != 1 {
+		t.Errorf("Refreshing made %d changes, expected none", changes-1)
+	}
+
+	preview.History().Redo()
+	editor.Refresh()
+	if got := editor.itemSelect.Selected; got != emptyItem {
+		t.Errorf("Expected %s to be selected after redo, got %q", emptyItem, got)
+	}
+	editor.Hide()
+}
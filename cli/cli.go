@@ -0,0 +1,337 @@
+// Package cli is the headless command line interface of hexgrid. It doesn't
+// depend on Fyne, so it builds and runs without a display server.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"hexgrid/generate"
+	"hexgrid/render"
+	"hexgrid/spec"
+)
+
+// usage describes the headless command line interface
+const usage = `Usage: hexgrid [command] [flags]
+
+Commands:
+  generate    Generate a hex grid from a YAML spec (default)
+  render      Re-render a saved .grid.yaml file: hexgrid render --grid FILE [--format F] [--out PATH]
+  validate    Check YAML specs for problems: hexgrid validate [file or directory...]
+              (default: grid-specs)
+  help        Show this help
+
+Without arguments, hexgrid starts the GUI and hexgrid-cli shows this help.
+
+Generate flags:
+`
+
+// commands are the commands Run runs
+var commands = []string{"generate", "render", "validate", "help"}
+
+// IsCommand reports whether command line arguments are for the CLI: the first
+// must be one of commands or a flag of the generate command. Anything
+// else, like the -psn_ argument macOS passes to an application opened from
+// the Finder, is left to the GUI.
+func IsCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	if slices.Contains(commands, args[0]) {
+		return true
+	}
+	name, isFlag := strings.CutPrefix(args[0], "-")
+	if !isFlag {
+		return false
+	}
+	name, _, _ = strings.Cut(strings.TrimPrefix(name, "-"), "=")
+	if name == "h" || name == "help" {
+		return true
+	}
+	return newGenerateFlags(&generate.Config{}, new(string), io.Discard).Lookup(name) != nil
+}
+
+// Run runs the headless command line interface and returns the process exit code
+func Run(args []string, stdout, stderr io.Writer) int {
+	command := "generate"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command = args[0]
+		args = args[1:]
+	}
+
+	switch command {
+	case "generate":
+		return runGenerate(args, stdout, stderr)
+	case "render":
+		return runRender(args, stdout, stderr)
+	case "validate":
+		return runValidate(args, stdout, stderr)
+	case "help":
+		fmt.Fprint(stdout, usage)
+		newGenerateFlags(&generate.Config{}, new(string), stdout).PrintDefaults()
+		return 0
+	default:
+		fmt.Fprintf(stderr, "hexgrid: unknown command %q\n\n", command)
+		fmt.Fprint(stderr, usage)
+		newGenerateFlags(&generate.Config{}, new(string), stderr).PrintDefaults()
+		return 2
+	}
+}
+
+// newGenerateFlags defines the flags of the generate command on config
+func newGenerateFlags(config *generate.Config, specPath *string, output io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.StringVar(specPath, "spec", "", "path to the YAML grid spec (required)")
+	flags.IntVar(&config.GridRows, "rows", 25, "number of grid rows")
+	flags.IntVar(&config.GridCols, "cols", 10, "number of grid columns")
+	flags.StringVar(&config.OutputFormat, "format", "svg", "output format: "+strings.Join(generate.Formats, ", "))
+	flags.Int64Var(&config.Seed, "seed", 0, "random seed for a reproducible grid (default: the spec's seed, or random)")
+	flags.StringVar(&config.OutputPath, "out", "", "output path; the extension is added for the format (default generated-grids/{spec}-{timestamp})")
+	addPageFlags(flags, &config.Page)
+	addRasterFlags(flags, &config.Raster)
+	return flags
+}
+
+// addPageFlags defines the PDF page setup flags, which override the spec's page settings
+func addPageFlags(flags *flag.FlagSet, page *generate.PageOverrides) {
+	flags.StringVar(&page.Size, "page-size", "", "PDF page size: "+strings.Join(spec.PageSizes, ", ")+" (default: the spec's, or A4)")
+	flags.StringVar(&page.Width, "page-width", "", "PDF page width for --page-size custom, like 24in or 600mm")
+	flags.StringVar(&page.Height, "page-height", "", "PDF page height for --page-size custom")
+	flags.StringVar(&page.Orientation, "orientation", "", "PDF page orientation: landscape or portrait (default: the spec's, or landscape)")
+	flags.StringVar(&page.Margin, "margin", "", "PDF page margin, like 20mm or 0.5in")
+	flags.StringVar(&page.HexSize, "hex-size", "", "PDF hex width across the flat sides, like 1in")
+	flags.Var(boolOverride{&page.Fit}, "fit", "scale the PDF hexes so the whole map fits on one page; --fit=false uses the hex size even if the spec fits (default: the spec's)")
+}
+
+// boolOverride is a boolean flag that stays nil unless it is given
+type boolOverride struct {
+	value **bool
+}
+
+// String implements flag.Value
+func (b boolOverride) String() string {
+	if b.value == nil || *b.value == nil {
+		return ""
+	}
+	return strconv.FormatBool(**b.value)
+}
+
+// Set implements flag.Value
+func (b boolOverride) Set(s string) error {
+	value, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*b.value = &value
+	return nil
+}
+
+// IsBoolFlag lets the flag be given without a value
+func (b boolOverride) IsBoolFlag() bool { return true }
+
+// addRasterFlags defines the flags that size PNG and JPEG images
+func addRasterFlags(flags *flag.FlagSet, raster *render.RasterOptions) {
+	flags.Float64Var(&raster.DPI, "dpi", 0, "PNG and JPEG resolution, where 96 is the size of the SVG (default 96)")
+	flags.IntVar(&raster.Width, "image-width", 0, "PNG and JPEG width in pixels, instead of --dpi")
+}
+
+// runGenerate implements the generate command
+func runGenerate(args []string, stdout, stderr io.Writer) int {
+	config := &generate.Config{}
+	var specPath string
+
+	flags := newGenerateFlags(config, &specPath, stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "hexgrid: unexpected arguments: %s\n", strings.Join(flags.Args(), " "))
+		return 2
+	}
+
+	if specPath == "" {
+		fmt.Fprintln(stderr, "hexgrid: --spec is required")
+		return 2
+	}
+	if config.GridRows <= 0 || config.GridCols <= 0 {
+		fmt.Fprintf(stderr, "hexgrid: grid size must be positive: %dx%d\n", config.GridRows, config.GridCols)
+		return 2
+	}
+	if !slices.Contains(generate.Formats, config.OutputFormat) {
+		fmt.Fprintf(stderr, "hexgrid: unknown output format: %s (must be one of %s)\n", config.OutputFormat, strings.Join(generate.Formats, ", "))
+		return 2
+	}
+
+	config.YAMLPath = specPath
+	if config.OutputPath == "" {
+		config.OutputPath = generate.DefaultOutputPath(config.YAMLPath)
+	} else {
+		outputPath, err := trimOutputExt(config.OutputPath, config.OutputFormat)
+		if err != nil {
+			fmt.Fprintf(stderr, "hexgrid: %v\n", err)
+			return 2
+		}
+		config.OutputPath = outputPath
+	}
+
+	hexGrid, err := generate.Grid(config)
+	if err != nil {
+		fmt.Fprintf(stderr, "hexgrid: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "%s.%s (seed %d)\n", config.OutputPath, generate.Ext(config.OutputFormat), hexGrid.Seed)
+	return 0
+}
+
+// runRender implements the render command, which renders a grid saved by
+// generate in another format without rerolling it
+func runRender(args []string, stdout, stderr io.Writer) int {
+	config := &generate.Config{}
+	var gridPath string
+
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&gridPath, "grid", "", "path to a .grid.yaml file saved by generate (required)")
+	flags.StringVar(&config.OutputFormat, "format", "svg", "output format: "+strings.Join(generate.Formats, ", "))
+	flags.StringVar(&config.OutputPath, "out", "", "output path; the extension is added for the format (default: next to the grid file)")
+	addPageFlags(flags, &config.Page)
+	addRasterFlags(flags, &config.Raster)
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if gridPath == "" || flags.NArg() > 0 {
+		fmt.Fprintln(stderr, "hexgrid: render needs --grid and no other arguments")
+		return 2
+	}
+
+	if config.OutputPath == "" {
+		config.OutputPath = strings.TrimSuffix(gridPath, ".grid.yaml")
+	} else {
+		outputPath, err := trimOutputExt(config.OutputPath, config.OutputFormat)
+		if err != nil {
+			fmt.Fprintf(stderr, "hexgrid: %v\n", err)
+			return 2
+		}
+		config.OutputPath = outputPath
+	}
+
+	hexGrid, err := generate.LoadGrid(gridPath)
+	if err == nil {
+		hexGrid, err = generate.WithPageSettings(hexGrid, config.Page)
+	}
+	if err == nil {
+		err = generate.WriteOutputs(config, hexGrid)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "hexgrid: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "%s.%s (seed %d)\n", config.OutputPath, generate.Ext(config.OutputFormat), hexGrid.Seed)
+	return 0
+}
+
+// runValidate implements the validate command, reporting every problem in
+// the given spec files and the .yaml files in the given directories
+func runValidate(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		args = []string{"grid-specs"}
+	}
+
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			fmt.Fprintf(stderr, "hexgrid: %v\n", err)
+			return 2
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+
+		entries, err := os.ReadDir(arg)
+		if err != nil {
+			fmt.Fprintf(stderr, "hexgrid: %v\n", err)
+			return 2
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && filepath.Ext(entry.Name()) == ".yaml" {
+				files = append(files, filepath.Join(arg, entry.Name()))
+			}
+		}
+	}
+
+	invalid := 0
+	for _, file := range files {
+		_, err := spec.Load(file)
+		var validationErr *spec.ValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			invalid++
+			for _, problem := range validationErr.Problems {
+				if problem.Column == 0 {
+					fmt.Fprintf(stderr, "%s:%d: %s\n", file, problem.Line, problem.Message)
+				} else {
+					fmt.Fprintf(stderr, "%s:%d:%d: %s\n", file, problem.Line, problem.Column, problem.Message)
+				}
+			}
+		case err != nil:
+			invalid++
+			fmt.Fprintf(stderr, "%s: %v\n", file, err)
+		default:
+			fmt.Fprintf(stdout, "%s: ok\n", file)
+		}
+	}
+
+	if invalid > 0 {
+		fmt.Fprintf(stderr, "%d of %d specs have problems\n", invalid, len(files))
+		return 1
+	}
+	return 0
+}
+
+// formatExts are the extensions of the files written for each output format
+var formatExts = map[string][]string{
+	"svg":  {".svg"},
+	"html": {".html", ".svg"},
+	"pdf":  {".pdf"},
+	"png":  {".png"},
+	"jpeg": {".jpg", ".jpeg"},
+}
+
+// trimOutputExt removes the extension of the output format from path, since
+// generate.Grid appends the extension for each file it writes. A path
+// ending in the extension of another format is an error, rather than a file
+// named like one format holding another.
+func trimOutputExt(path, format string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if slices.Contains(formatExts[format], ext) {
+		return strings.TrimSuffix(path, filepath.Ext(path)), nil
+	}
+	for _, other := range generate.Formats {
+		if slices.Contains(formatExts[other], ext) {
+			return "", fmt.Errorf("output path %s is for the %s format, not %s", path, other, format)
+		}
+	}
+	return path, nil
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCLIGenerate(t *testing.T) {
+	outDir := t.TempDir()
+	outPath := filepath.Join(outDir, "dice")
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"generate", "--spec", filepath.Join("..", "grid-specs", "dice-test.yaml"),
+		"--rows", "4", "--cols", "3", "--format", "html", "--out", outPath + ".html"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+
+	for _, ext := range []string{".svg", ".html", ".grid.yaml"} {
+		if _, err := os.Stat(outPath + ext); err != nil {
+			t.Errorf("Expected output file %s: %v", outPath+ext, err)
+		}
+	}
+
+	// The saved grid can be rendered again in another format
+	stdout.Reset()
+	stderr.Reset()
+	code = Run([]string{"render", "--grid", outPath + ".grid.yaml", "--format", "pdf",
+		"--page-size", "Letter", "--orientation", "portrait", "--hex-size", "1in", "--fit"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("Expected exit code 0 from render, got %d: %s", code, stderr.String())
+	}
+	if _, err := os.Stat(outPath + ".pdf"); err != nil {
+		t.Errorf("Expected rendered PDF: %v", err)
+	}
+
+	// and as an image of a chosen width
+	stdout.Reset()
+	stderr.Reset()
+	code = Run([]string{"render", "--grid", outPath + ".grid.yaml", "--format", "png", "--image-width", "300"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("Expected exit code 0 from render, got %d: %s", code, stderr.String())
+	}
+	file, err := os.Open(outPath + ".png")
+	if err != nil {
+		t.Fatalf("Expected rendered PNG: %v", err)
+	}
+	defer file.Close()
+	img, err := png.DecodeConfig(file)
+	if err != nil {
+		t.Fatalf("Failed to decode PNG: %v", err)
+	}
+	if img.Width != 300 {
+		t.Errorf("Expected a PNG 300 pixels wide, got %d", img.Width)
+	}
+}
+
+func TestRunCLIErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+		msg  string
+	}{
+		{"missing spec", []string{"--rows", "3"}, 2, "--spec is required"},
+		{"bad format", []string{"--spec", "x.yaml", "--format", "gif"}, 2, "unknown output format"},
+		{"unknown command", []string{"frobnicate"}, 2, "unknown command"},
+		{"mismatched extension", []string{"--spec", filepath.Join("..", "grid-specs", "dice-test.yaml"), "--format", "pdf", "--out", filepath.Join(t.TempDir(), "out.png")}, 2, "is for the png format, not pdf"},
+		{"bad page size", []string{"--spec", filepath.Join("..", "grid-specs", "dice-test.yaml"), "--format", "pdf", "--page-size", "B5", "--out", filepath.Join(t.TempDir(), "out")}, 1, "invalid page size: B5"},
+		{"unreadable spec", []string{"--spec", filepath.Join(t.TempDir(), "missing.yaml"), "--out", filepath.Join(t.TempDir(), "out")}, 1, "failed to load YAML config"},
+	}
+
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		code := Run(tt.args, &stdout, &stderr)
+		if code != tt.code {
+			t.Errorf("%s: expected exit code %d, got %d", tt.name, tt.code, code)
+		}
+		if !strings.Contains(stderr.String(), tt.msg) {
+			t.Errorf("%s: expected error containing %q, got %q", tt.name, tt.msg, stderr.String())
+		}
+	}
+}
+
+func TestRunCLIValidate(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"validate", filepath.Join("..", "grid-specs")}, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected the grid-specs to be valid, got exit code %d: %s", code, stderr.String())
+	}
+
+	badSpec := filepath.Join(t.TempDir(), "bad.yaml")
+	err := os.WriteFile(badSpec, []byte("default: \"#FFFFFF\"\nitems:\n  - name: \"Star\"\n    style: \"dot\"\n    color: \"#12345\"\n    size: \"huge\"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := Run([]string{"validate", badSpec}, &stdout, &stderr); code != 1 {
+		t.Errorf("Expected exit code 1 for an invalid spec, got %d", code)
+	}
+	for _, want := range []string{badSpec + ":5:12: invalid color", badSpec + ":6:11: invalid size"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Errorf("Expected %q in output:\n%s", want, stderr.String())
+		}
+	}
+}
+
+func TestIsCommand(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want bool
+	}{
+		{nil, false},
+		{[]string{"-psn_0_1234567"}, false},
+		{[]string{"--spec", "map.yaml"}, true},
+		{[]string{"-rows=3"}, true},
+		{[]string{"--help"}, true},
+		{[]string{"render", "--grid", "map.grid.yaml"}, true},
+		{[]string{"validate"}, true},
+		{[]string{"map.yaml"}, false},
+	} {
+		if got := IsCommand(tt.args); got != tt.want {
+			t.Errorf("IsCommand(%q) = %v, expected %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestTrimOutputExt(t *testing.T) {
+	for _, tt := range []struct {
+		path, format, want string
+		ok                 bool
+	}{
+		{"maps/out.pdf", "pdf", "maps/out", true},
+		{"maps/out.html", "html", "maps/out", true},
+		{"maps/out.svg", "html", "maps/out", true},
+		{"maps/out.JPEG", "jpeg", "maps/out", true},
+		{"maps/out", "png", "maps/out", true},
+		{"maps/out.v2", "png", "maps/out.v2", true},
+		{"maps/out.html", "svg", "", false},
+		{"maps/out.png", "jpeg", "", false},
+	} {
+		got, err := trimOutputExt(tt.path, tt.format)
+		if got != tt.want || (err == nil) != tt.ok {
+			t.Errorf("trimOutputExt(%q, %q) = %q, %v, expected %q", tt.path, tt.format, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+// Command hexgrid-cli is the hexgrid command line without the GUI, so it
+// builds without cgo or a display server, for servers and CI.
+package main
+
+import (
+	"os"
+
+	"hexgrid/cli"
+)
+
+func main() {
+	args := os.Args[1:]
+	if len(args) == 0 {
+		args = []string{"help"}
+	}
+	os.Exit(cli.Run(args, os.Stdout, os.Stderr))
+}
@@ -1,216 +0,0 @@
-package main
-
-import (
-	"fmt"
-	"io/ioutil"
-	"math/rand"
-	"regexp"
-	"strconv"
-	"strings"
-
-	"gopkg.in/yaml.v3"
-)
-
-// ItemType represents a type of item that can be placed in the hex grid
-type ItemType struct {
-	Name       string  `yaml:"name"`
-	Percentage float64 `yaml:"percentage"`
-	Style      string  `yaml:"style"` // "dot" or "fill"
-	Color      string  `yaml:"color"`
-	Dice       string  `yaml:"dice,omitempty"`   // Optional dice notation like "2d6" or "3d8"
-	Letter     string  `yaml:"letter,omitempty"` // Optional letter like "F", "G", "K", "M", "N", etc.
-	Size       string  `yaml:"size,omitempty"`   // Optional size like "small", "large", "x-large", "xx-large"
-}
-
-// Config represents the YAML configuration file structure
-type YAMLConfig struct {
-	Default string     `yaml:"default"`
-	Items   []ItemType `yaml:"items"`
-}
-
-// HexCell represents a single hexagon cell in the grid
-type HexCell struct {
-	Row        int
-	Col        int
-	ItemType   *ItemType
-	X, Y       float64     // Center coordinates
-	DiceResult *DiceResult // Dice roll result if item has dice
-}
-
-// HexGrid represents the complete hex grid
-type HexGrid struct {
-	Rows         int
-	Cols         int
-	Cells        [][]*HexCell
-	ItemTypes    []*ItemType
-	DefaultColor string
-}
-
-// LoadYAMLConfig loads and parses the YAML configuration file
-func LoadYAMLConfig(filePath string) (*YAMLConfig, error) {
-	data, err := ioutil.ReadFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read YAML file: %w", err)
-	}
-
-	var config YAMLConfig
-	err = yaml.Unmarshal(data, &config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse YAML: %w", err)
-	}
-
-	// Validate configuration
-	if config.Default == "" {
-		return nil, fmt.Errorf("default color is required")
-	}
-	if len(config.Items) == 0 {
-		return nil, fmt.Errorf("no items defined in configuration")
-	}
-
-	totalPercentage := 0.0
-	for _, item := range config.Items {
-		if item.Percentage < 0 || item.Percentage > 100 {
-			return nil, fmt.Errorf("invalid percentage for item %s: %f", item.Name, item.Percentage)
-		}
-		totalPercentage += item.Percentage
-		if item.Style != "dot" && item.Style != "fill" {
-			return nil, fmt.Errorf("invalid style for item %s: %s (must be 'dot' or 'fill')", item.Name, item.Style)
-		}
-	}
-
-	if totalPercentage > 100 {
-		return nil, fmt.Errorf("total percentage exceeds 100%%: %f", totalPercentage)
-	}
-
-	return &config, nil
-}
-
-// CreateHexGrid creates a new hex grid with the specified dimensions
-func CreateHexGrid(rows, cols int, config *YAMLConfig) *HexGrid {
-	grid := &HexGrid{
-		Rows:         rows,
-		Cols:         cols,
-		Cells:        make([][]*HexCell, rows),
-		ItemTypes:    make([]*ItemType, len(config.Items)),
-		DefaultColor: config.Default,
-	}
-
-	// Copy item types
-	for i := range config.Items {
-		grid.ItemTypes[i] = &config.Items[i]
-	}
-
-	// Initialize cells
-	for row := 0; row < rows; row++ {
-		grid.Cells[row] = make([]*HexCell, cols)
-		for col := 0; col < cols; col++ {
-			grid.Cells[row][col] = &HexCell{
-				Row: row,
-				Col: col,
-			}
-		}
-	}
-
-	return grid
-}
-
-// PopulateGrid fills the grid with items based on their percentages
-func (grid *HexGrid) PopulateGrid() {
-	totalCells := grid.Rows * grid.Cols
-
-	// Calculate how many cells each item type should occupy
-	itemCounts := make(map[*ItemType]int)
-	for _, itemType := range grid.ItemTypes {
-		count := int(float64(totalCells) * itemType.Percentage / 100.0)
-		itemCounts[itemType] = count
-	}
-
-	// Create a list of all cells
-	allCells := make([]*HexCell, 0, totalCells)
-	for row := 0; row < grid.Rows; row++ {
-		for col := 0; col < grid.Cols; col++ {
-			allCells = append(allCells, grid.Cells[row][col])
-		}
-	}
-
-	// Shuffle the cells
-	rand.Shuffle(len(allCells), func(i, j int) {
-		allCells[i], allCells[j] = allCells[j], allCells[i]
-	})
-
-	// Assign items to cells
-	cellIndex := 0
-	for itemType, count := range itemCounts {
-		for i := 0; i < count && cellIndex < len(allCells); i++ {
-			allCells[cellIndex].ItemType = itemType
-
-			// Roll dice if the item has dice notation
-			if itemType.Dice != "" {
-				diceResult, err := rollDice(itemType.Dice)
-				if err != nil {
-					// Log error but continue - don't break the grid generation
-					fmt.Printf("Warning: failed to roll dice for %s (%s): %v\n", itemType.Name, itemType.Dice, err)
-				} else {
-					allCells[cellIndex].DiceResult = diceResult
-				}
-			}
-
-			cellIndex++
-		}
-	}
-}
-
-// DiceResult represents the result of rolling dice
-type DiceResult struct {
-	Total int
-	Rolls []int
-}
-
-// parseDiceNotation parses dice notation like "2d6" or "3d8"
-func parseDiceNotation(diceStr string) (int, int, error) {
-	// Remove whitespace and convert to lowercase
-	diceStr = strings.ToLower(strings.TrimSpace(diceStr))
-
-	// Match pattern like "2d6" or "3d8"
-	re := regexp.MustCompile(`^(\d+)d(\d+)$`)
-	matches := re.FindStringSubmatch(diceStr)
-
-	if len(matches) != 3 {
-		return 0, 0, fmt.Errorf("invalid dice notation: %s (expected format like '2d6')", diceStr)
-	}
-
-	numDice, err := strconv.Atoi(matches[1])
-	if err != nil {
-		return 0, 0, fmt.Errorf("invalid number of dice: %s", matches[1])
-	}
-
-	diceSides, err := strconv.Atoi(matches[2])
-	if err != nil {
-		return 0, 0, fmt.Errorf("invalid dice sides: %s", matches[2])
-	}
-
-	if numDice <= 0 || diceSides <= 0 {
-		return 0, 0, fmt.Errorf("dice count and sides must be positive")
-	}
-
-	return numDice, diceSides, nil
-}
-
-// rollDice rolls the specified dice and returns the result
-func rollDice(diceStr string) (*DiceResult, error) {
-	numDice, diceSides, err := parseDiceNotation(diceStr)
-	if err != nil {
-		return nil, err
-	}
-
-	result := &DiceResult{
-		Rolls: make([]int, numDice),
-	}
-
-	for i := 0; i < numDice; i++ {
-		result.Rolls[i] = rand.Intn(diceSides) + 1
-		result.Total += result.Rolls[i]
-	}
-
-	return result, nil
-}
@@ -0,0 +1,508 @@
+// Package dice parses and rolls dice expressions such as "2d6+3", "4d6kh3",
+// "d66", "d%", "3d6!" and "3d6*10".
+//
+// An expression combines integers and dice terms with +, -, * and / (integer
+// division) and parentheses. A dice term is written [count]d<sides>, where
+// sides may be "%" for a d100 and "66" for the two-digit d66 (a d6 for the
+// tens and another for the units). A dice term may be followed by modifiers:
+//
+//	!    exploding: every die that rolls its maximum is rolled again and added
+//	khN  keep the N highest dice (also kN)
+//	klN  keep the N lowest dice
+//	dhN  drop the N highest dice
+//	dlN  drop the N lowest dice (also dN)
+package dice
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Limits that keep expressions and explosions to a sane size
+const (
+	maxCount      = 1000
+	maxSides      = 1000000
+	maxExplosions = 100
+)
+
+// Result represents the result of rolling a dice expression
+type Result struct {
+	Total     int
+	Rolls     []int  // Values of the dice that count towards the total, in order
+	Breakdown string // Expression with every roll shown, e.g. "4d6kh3[6,5,3,(1)]+3"
+}
+
+// Expr is a parsed dice expression
+type Expr struct {
+	source string
+	root   node
+}
+
+// Parse parses a dice expression, rejecting expressions that are malformed,
+// too large, or that could divide by zero. Every part of an expression, and
+// so every total it rolls, lies within the int32 range.
+func Parse(s string) (*Expr, error) {
+	p := &parser{input: strings.ToLower(strings.Join(strings.Fields(s), ""))}
+	if p.input == "" {
+		return nil, fmt.Errorf("empty dice expression")
+	}
+
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid dice expression %q: %w", s, err)
+	}
+	if p.pos < len(p.input) {
+		return nil, fmt.Errorf("invalid dice expression %q: unexpected %q at position %d", s, p.input[p.pos], p.pos+1)
+	}
+
+	return &Expr{source: strings.TrimSpace(s), root: root}, nil
+}
+
+// Roll parses and rolls a dice expression using rng
+func Roll(rng *rand.Rand, s string) (*Result, error) {
+	expr, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return expr.Roll(rng), nil
+}
+
+// Roll rolls the expression using rng
+func (e *Expr) Roll(rng *rand.Rand) *Result {
+	result := &Result{}
+	total, breakdown := e.root.eval(rng, result)
+	result.Total = total
+	result.Breakdown = breakdown
+	return result
+}
+
+// Min returns the smallest total the expression can roll
+func (e *Expr) Min() int {
+	low, _ := e.root.bounds()
+	return int(low)
+}
+
+// Max returns the largest total the expression can roll. An exploding die
+// is rolled again at most maxExplosions times, which bounds its total.
+func (e *Expr) Max() int {
+	_, high := e.root.bounds()
+	return int(high)
+}
+
+// String returns the expression as it was written
+func (e *Expr) String() string {
+	return e.source
+}
+
+// node is an element of a parsed expression
+type node interface {
+	// eval returns the node's value and breakdown, appending counted dice to result.Rolls
+	eval(rng *rand.Rand, result *Result) (int, string)
+	// bounds returns the smallest and largest values the node can take
+	bounds() (float64, float64)
+}
+
+// number is an integer constant
+type number int
+
+func (n number) eval(*rand.Rand, *Result) (int, string) {
+	return int(n), strconv.Itoa(int(n))
+}
+
+func (n number) bounds() (float64, float64) {
+	return float64(n), float64(n)
+}
+
+// negate is a unary minus
+type negate struct {
+	operand node
+}
+
+func (n negate) eval(rng *rand.Rand, result *Result) (int, string) {
+	value, breakdown := n.operand.eval(rng, result)
+	return -value, "-" + breakdown
+}
+
+func (n negate) bounds() (float64, float64) {
+	low, high := n.operand.bounds()
+	return -high, -low
+}
+
+// group is a parenthesized expression
+type group struct {
+	inner node
+}
+
+func (g group) eval(rng *rand.Rand, result *Result) (int, string) {
+	value, breakdown := g.inner.eval(rng, result)
+	return value, "(" + breakdown + ")"
+}
+
+func (g group) bounds() (float64, float64) {
+	return g.inner.bounds()
+}
+
+// binary is an arithmetic operation on two nodes
+type binary struct {
+	op          byte
+	left, right node
+}
+
+func (b binary) eval(rng *rand.Rand, result *Result) (int, string) {
+	left, leftBreakdown := b.left.eval(rng, result)
+	right, rightBreakdown := b.right.eval(rng, result)
+
+	var value int
+	switch b.op {
+	case '+':
+		value = left + right
+	case '-':
+		value = left - right
+	case '*':
+		value = left * right
+	case '/':
+		value = left / right // the parser rejects divisors that can be zero
+	}
+	return value, leftBreakdown + string(b.op) + rightBreakdown
+}
+
+func (b binary) bounds() (float64, float64) {
+	leftLow, leftHigh := b.left.bounds()
+	rightLow, rightHigh := b.right.bounds()
+
+	switch b.op {
+	case '+':
+		return leftLow + rightLow, leftHigh + rightHigh
+	case '-':
+		return leftLow - rightHigh, leftHigh - rightLow
+	}
+
+	// The extremes of a product or quotient lie at the corners
+	corners := make([]float64, 0, 4)
+	for _, l := range []float64{leftLow, leftHigh} {
+		for _, r := range []float64{rightLow, rightHigh} {
+			var corner float64
+			if b.op == '*' {
+				corner = l * r
+			} else {
+				corner = math.Trunc(l / r)
+			}
+			corners = append(corners, corner)
+		}
+	}
+	sort.Float64s(corners)
+	return corners[0], corners[len(corners)-1]
+}
+
+// Keep modes for roll.keep
+const (
+	keepAll = iota
+	keepHighest
+	keepLowest
+)
+
+// roll is a dice term such as "4d6kh3"
+type roll struct {
+	count   int
+	sides   int
+	d66     bool // roll a d6 for the tens and a d6 for the units
+	explode bool
+	keep    int // keepAll, keepHighest or keepLowest
+	keepN   int // number of dice kept
+	source  string
+}
+
+func (r roll) eval(rng *rand.Rand, result *Result) (int, string) {
+	values := make([]int, r.count)
+	labels := make([]string, r.count)
+	for i := range values {
+		if r.d66 {
+			tens, units := rng.Intn(6)+1, rng.Intn(6)+1
+			values[i] = tens*10 + units
+			labels[i] = strconv.Itoa(values[i])
+			continue
+		}
+
+		value := rng.Intn(r.sides) + 1
+		label := strconv.Itoa(value)
+		if r.explode {
+			last := value
+			for n := 0; last == r.sides && n < maxExplosions; n++ {
+				last = rng.Intn(r.sides) + 1
+				value += last
+				label += "!" + strconv.Itoa(last)
+			}
+		}
+		values[i] = value
+		labels[i] = label
+	}
+
+	// Work out which dice are kept, preferring earlier dice on ties
+	kept := make([]bool, r.count)
+	order := make([]int, r.count)
+	for i := range order {
+		order[i] = i
+		kept[i] = r.keep == keepAll
+	}
+	if r.keep != keepAll {
+		sort.SliceStable(order, func(a, b int) bool {
+			if r.keep == keepHighest {
+				return values[order[a]] > values[order[b]]
+			}
+			return values[order[a]] < values[order[b]]
+		})
+		for _, i := range order[:r.keepN] {
+			kept[i] = true
+		}
+	}
+
+	total := 0
+	for i, value := range values {
+		if kept[i] {
+			total += value
+			result.Rolls = append(result.Rolls, value)
+		} else {
+			labels[i] = "(" + labels[i] + ")"
+		}
+	}
+	return total, r.source + "[" + strings.Join(labels, ",") + "]"
+}
+
+func (r roll) bounds() (float64, float64) {
+	n := float64(r.count)
+	if r.keep != keepAll {
+		n = float64(r.keepN)
+	}
+	if r.d66 {
+		return n * 11, n * 66
+	}
+	if r.explode {
+		return n, n * float64(r.sides) * (maxExplosions + 1)
+	}
+	return n, n * float64(r.sides)
+}
+
+// checkRange rejects a node that can take values outside the int32 range,
+// so that no total or part of one can overflow an int
+func checkRange(n node) (node, error) {
+	if low, high := n.bounds(); low < math.MinInt32 || high > math.MaxInt32 {
+		return nil, fmt.Errorf("totals can reach %.0f to %.0f, outside %d to %d", low, high, math.MinInt32, math.MaxInt32)
+	}
+	return n, nil
+}
+
+// parser is a recursive descent parser over a lowercased expression without whitespace
+type parser struct {
+	input string
+	pos   int
+}
+
+// parseExpr parses terms separated by + and -
+func (p *parser) parseExpr() (node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == '+' || p.peek() == '-' {
+		op := p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = checkRange(binary{op: op, left: left, right: right}); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+// parseTerm parses factors separated by * and /
+func (p *parser) parseTerm() (node, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == '*' || p.peek() == '/' {
+		op := p.next()
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		if op == '/' {
+			if low, high := right.bounds(); low <= 0 && high >= 0 {
+				return nil, fmt.Errorf("divisor can be zero")
+			}
+		}
+		if left, err = checkRange(binary{op: op, left: left, right: right}); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+// parseFactor parses a number, a dice term, a negation or a parenthesized expression
+func (p *parser) parseFactor() (node, error) {
+	switch c := p.peek(); {
+	case c == '-':
+		p.next()
+		operand, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return checkRange(negate{operand})
+	case c == '(':
+		p.next()
+		inner, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ')' {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		return group{inner}, nil
+	case c == 'd':
+		return p.parseRoll(1, p.pos)
+	case isDigit(c):
+		start := p.pos
+		n, err := p.parseNumber()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() == 'd' {
+			return p.parseRoll(n, start)
+		}
+		return number(n), nil
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of expression")
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %d", c, p.pos+1)
+	}
+}
+
+// parseRoll parses a dice term from its "d", given the already parsed count
+func (p *parser) parseRoll(count, start int) (node, error) {
+	p.next() // 'd'
+	r := roll{count: count}
+
+	switch {
+	case p.peek() == '%':
+		p.next()
+		r.sides = 100
+	case isDigit(p.peek()):
+		sides, err := p.parseNumber()
+		if err != nil {
+			return nil, err
+		}
+		r.sides = sides
+		r.d66 = sides == 66
+	default:
+		return nil, fmt.Errorf("missing number of sides at position %d", p.pos+1)
+	}
+
+	if r.count < 1 || r.count > maxCount {
+		return nil, fmt.Errorf("number of dice must be between 1 and %d: %d", maxCount, r.count)
+	}
+	if r.sides < 1 || r.sides > maxSides {
+		return nil, fmt.Errorf("dice sides must be between 1 and %d: %d", maxSides, r.sides)
+	}
+
+	// Modifiers
+	for {
+		switch {
+		case p.peek() == '!':
+			if r.explode {
+				return nil, fmt.Errorf("only one explode modifier is allowed at position %d", p.pos+1)
+			}
+			p.next()
+			if r.sides < 2 || r.d66 {
+				return nil, fmt.Errorf("d%d dice cannot explode", r.sides)
+			}
+			r.explode = true
+		case p.peek() == 'k' || p.peek() == 'd':
+			// A new dice term needs an operator first, so a "d" straight
+			// after a dice term is always a drop modifier
+			if err := p.parseKeep(&r); err != nil {
+				return nil, err
+			}
+		default:
+			r.source = p.input[start:p.pos]
+			return checkRange(r)
+		}
+	}
+}
+
+// isDigit reports whether c is an ASCII digit
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// parseKeep parses a keep or drop modifier: kh, kl, k, dh, dl or d followed by a count
+func (p *parser) parseKeep(r *roll) error {
+	if r.keep != keepAll {
+		return fmt.Errorf("only one keep or drop modifier is allowed at position %d", p.pos+1)
+	}
+
+	kind := string(p.next())
+	if c := p.peek(); c == 'h' || c == 'l' {
+		kind += string(p.next())
+	}
+	if !isDigit(p.peek()) {
+		return fmt.Errorf("missing count for %q modifier at position %d", kind, p.pos+1)
+	}
+	n, err := p.parseNumber()
+	if err != nil {
+		return err
+	}
+	if r.d66 {
+		return fmt.Errorf("d66 dice cannot be kept or dropped")
+	}
+
+	switch kind {
+	case "k", "kh":
+		r.keep, r.keepN = keepHighest, n
+	case "kl":
+		r.keep, r.keepN = keepLowest, n
+	case "dh":
+		r.keep, r.keepN = keepLowest, r.count-n
+	case "d", "dl":
+		r.keep, r.keepN = keepHighest, r.count-n
+	}
+	if r.keepN < 1 || r.keepN > r.count {
+		return fmt.Errorf("%q modifier must leave between 1 and %d dice", kind+strconv.Itoa(n), r.count)
+	}
+	return nil
+}
+
+// parseNumber parses a non-negative decimal integer
+func (p *parser) parseNumber() (int, error) {
+	start := p.pos
+	for isDigit(p.peek()) {
+		p.next()
+	}
+	n, err := strconv.Atoi(p.input[start:p.pos])
+	if err != nil || n > maxSides {
+		return 0, fmt.Errorf("number too large at position %d", start+1)
+	}
+	return n, nil
+}
+
+// peek returns the next character without consuming it, or 0 at the end
+func (p *parser) peek() byte {
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+// next consumes and returns the next character, or 0 at the end
+func (p *parser) next() byte {
+	c := p.peek()
+	if p.pos < len(p.input) {
+		p.pos++
+	}
+	return c
+}
@@ -0,0 +1,156 @@
+package dice
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestRollRanges(t *testing.T) {
+	tests := []struct {
+		expr     string
+		min, max int
+		rolls    int // number of counted dice
+	}{
+		{"2d6", 2, 12, 2},
+		{"2d6+3", 5, 15, 2},
+		{"1d20-1", 0, 19, 1},
+		{"d%", 1, 100, 1},
+		{"D8", 1, 8, 1},
+		{"d66", 11, 66, 1},
+		{"4d6kh3", 3, 18, 3},
+		{"4d6k3", 3, 18, 3},
+		{"4d6kl1", 1, 6, 1},
+		{"4d6dl1", 3, 18, 3},
+		{"4d6d1", 3, 18, 3},
+		{"3d6dh1", 2, 12, 2},
+		{"3d6*10", 30, 180, 3},
+		{"(1d4+1)*2", 4, 10, 1},
+		{"2d6 / 2", 1, 6, 2},
+		{"-1d4+10", 6, 9, 1},
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for _, tt := range tests {
+		expr, err := Parse(tt.expr)
+		if err != nil {
+			t.Errorf("%s: failed to parse: %v", tt.expr, err)
+			continue
+		}
+		if expr.Min() != tt.min || expr.Max() != tt.max {
+			t.Errorf("%s: expected bounds %d-%d, got %d-%d", tt.expr, tt.min, tt.max, expr.Min(), expr.Max())
+		}
+
+		seen := make(map[int]bool)
+		for i := 0; i < 20000; i++ {
+			result := expr.Roll(rng)
+			if result.Total < tt.min || result.Total > tt.max {
+				t.Fatalf("%s: rolled %d outside %d-%d (%s)", tt.expr, result.Total, tt.min, tt.max, result.Breakdown)
+			}
+			if len(result.Rolls) != tt.rolls {
+				t.Fatalf("%s: expected %d counted dice, got %v", tt.expr, tt.rolls, result.Rolls)
+			}
+			seen[result.Total] = true
+		}
+		if !seen[tt.min] || !seen[tt.max] {
+			t.Errorf("%s: expected both extremes to be rolled in 20000 tries", tt.expr)
+		}
+	}
+}
+
+func TestD66Digits(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 500; i++ {
+		result, err := Roll(rng, "d66")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tens, units := result.Total/10, result.Total%10
+		if tens < 1 || tens > 6 || units < 1 || units > 6 {
+			t.Fatalf("d66 rolled %d, expected two digits from 1 to 6", result.Total)
+		}
+	}
+}
+
+func TestExplodingDice(t *testing.T) {
+	expr, err := Parse("3d6!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expr.Min() != 3 || expr.Max() != 3*6*(maxExplosions+1) {
+		t.Errorf("Expected bounds 3-%d, got %d-%d", 3*6*(maxExplosions+1), expr.Min(), expr.Max())
+	}
+
+	rng := rand.New(rand.NewSource(3))
+	exploded := false
+	for i := 0; i < 1000; i++ {
+		result := expr.Roll(rng)
+		if result.Total > 18 {
+			exploded = true
+			if !strings.Contains(result.Breakdown, "6!") {
+				t.Errorf("Expected explosion in breakdown %q", result.Breakdown)
+			}
+		}
+	}
+	if !exploded {
+		t.Error("Expected at least one roll to explode past 18")
+	}
+}
+
+func TestBreakdown(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	result, err := Roll(rng, "4d6kh3 + 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(result.Breakdown, "4d6kh3[") || !strings.HasSuffix(result.Breakdown, "]+2") {
+		t.Errorf("Unexpected breakdown %q", result.Breakdown)
+	}
+	if strings.Count(result.Breakdown, "(") != 1 {
+		t.Errorf("Expected exactly one dropped die in %q", result.Breakdown)
+	}
+
+	sum := 2
+	for _, roll := range result.Rolls {
+		sum += roll
+	}
+	if sum != result.Total {
+		t.Errorf("Counted rolls %v plus 2 don't add up to total %d", result.Rolls, result.Total)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"", "d", "2d", "2x6", "2d6+", "(2d6", "2d6)", "0d6", "2d0", "1d1!", "d66!",
+		"4d6kh5", "4d6kh0", "4d6kh", "4d6kh1kl1", "2d6!!", "4d6!kh3!", "2d6/0", "10/(1d3-1)", "99999999d6",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Expected %q to be rejected", expr)
+		}
+	}
+}
+
+func TestParseOutOfRange(t *testing.T) {
+	// Each of these fits the dice limits but could roll past the int32 range,
+	// even if only in part of the expression
+	for _, expr := range []string{
+		"1000d1000000*1000d1000000",
+		"-(1000d1000000*1000d1000000)",
+		"(1000d1000000*1000d1000000)/1000d1000000",
+		"1000d1000000!",
+		"1000d1000000*3-1",
+	} {
+		if _, err := Parse(expr); err == nil || !strings.Contains(err.Error(), "outside") {
+			t.Errorf("Expected %q to be rejected as out of range, got %v", expr, err)
+		}
+	}
+
+	// The largest totals that fit are still allowed
+	expr, err := Parse("1000d1000000*2")
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if expr.Max() != 2000000000 {
+		t.Errorf("Expected a maximum of 2000000000, got %d", expr.Max())
+	}
+}
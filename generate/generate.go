@@ -0,0 +1,274 @@
+// Package generate creates grids from spec files and writes them in the
+// output formats. It is shared by the GUI and the command line, and doesn't
+// depend on Fyne.
+package generate
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"hexgrid/grid"
+	"hexgrid/render"
+	"hexgrid/spec"
+)
+
+// Config describes a grid to generate and the files to write it to
+type Config struct {
+	YAMLPath     string
+	OutputPath   string
+	GridRows     int
+	GridCols     int
+	OutputFormat string // One of Formats
+	Seed         int64  // Random seed; 0 uses the spec's seed or a random one
+
+	// Page setup for PDF output, overriding the spec's page settings
+	Page PageOverrides
+
+	// Resolution or pixel size of PNG and JPEG output
+	Raster render.RasterOptions
+}
+
+// PageOverrides are PDF page settings that replace the spec's: each string
+// that isn't empty, and Fit if it isn't nil, so fitting can be turned off
+// as well as on
+type PageOverrides struct {
+	Size        string
+	Width       string
+	Height      string
+	Orientation string
+	Margin      string
+	HexSize     string
+	Fit         *bool
+}
+
+// Formats are the valid values of Config.OutputFormat
+var Formats = []string{"svg", "html", "pdf", "png", "jpeg"}
+
+// Ext returns the extension of the main file written for an output format
+func Ext(format string) string {
+	if format == "jpeg" {
+		return "jpg"
+	}
+	return format
+}
+
+// DefaultOutputPath returns the timestamped output path (without extension)
+// in the generated-grids directory for the given YAML file
+func DefaultOutputPath(yamlPath string) string {
+	// Get the base name of the YAML file (without extension)
+	yamlBaseName := filepath.Base(yamlPath)
+	yamlNameWithoutExt := yamlBaseName[:len(yamlBaseName)-len(filepath.Ext(yamlBaseName))]
+
+	// Generate timestamp
+	timestamp := time.Now().Format("2006-01-02-15-04-05")
+
+	// Get the executable path to find the app bundle location
+	execPath, err := os.Executable()
+	if err != nil {
+		execPath = "."
+	}
+
+	// Check if we're running from an app bundle
+	appBundlePath := filepath.Join(filepath.Dir(execPath), "..", "..", "..")
+	generatedGridsDir := filepath.Join(appBundlePath, "Contents", "Resources", "generated-grids")
+
+	// If not in app bundle, try current directory
+	if _, err := os.Stat(generatedGridsDir); os.IsNotExist(err) {
+		currentDir, err := os.Getwd()
+		if err != nil {
+			currentDir = "."
+		}
+		generatedGridsDir = filepath.Join(currentDir, "generated-grids")
+	}
+
+	// Create generated-grids directory if it doesn't exist
+	if _, err := os.Stat(generatedGridsDir); os.IsNotExist(err) {
+		os.MkdirAll(generatedGridsDir, 0755)
+	}
+
+	// Generate output path
+	outputFileName := fmt.Sprintf("%s-%s", yamlNameWithoutExt, timestamp)
+	return filepath.Join(generatedGridsDir, outputFileName)
+}
+
+// Grid creates and populates a grid for config and writes its output files
+func Grid(config *Config) (*grid.HexGrid, error) {
+	hexGrid, err := NewGrid(config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = Save(config, hexGrid)
+	if err != nil {
+		return nil, err
+	}
+	return hexGrid, nil
+}
+
+// NewGrid loads the spec and creates and populates a grid of the
+// configured size
+func NewGrid(config *Config) (*grid.HexGrid, error) {
+	// Load YAML configuration
+	yamlConfig, err := spec.Load(config.YAMLPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load YAML config: %w", err)
+	}
+
+	// Create hex grid
+	hexGrid := grid.New(config.GridRows, config.GridCols, yamlConfig)
+
+	// The seed from the GUI or command line overrides the spec's seed;
+	// without either, pick a random one so it can be printed on the map
+	if config.Seed != 0 {
+		hexGrid.Seed = config.Seed
+	}
+	if hexGrid.Seed == 0 {
+		hexGrid.Seed = grid.NewSeed()
+	}
+
+	// Populate grid with items
+	err = hexGrid.Populate(rand.New(rand.NewSource(hexGrid.Seed)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to populate grid: %w", err)
+	}
+
+	return hexGrid, nil
+}
+
+// Save writes the output files of a populated grid, such as the one
+// shown in the preview, without rerolling it
+func Save(config *Config, hexGrid *grid.HexGrid) error {
+	paged, err := WithPageSettings(hexGrid, config.Page)
+	if err != nil {
+		return err
+	}
+
+	err = WriteOutputs(config, paged)
+	if err != nil {
+		return err
+	}
+
+	// Save the grid data alongside the pictures so it can be re-rendered later
+	err = writeOutputFile(config.OutputPath+".grid.yaml", hexGrid, saveGrid)
+	if err != nil {
+		return fmt.Errorf("failed to save grid: %w", err)
+	}
+	return nil
+}
+
+// WithPageSettings returns a copy of the grid with the page overrides
+// applied to its page settings. The grid itself, and so the spec saved with
+// it, keeps its own settings; the copy shares its cells.
+func WithPageSettings(hexGrid *grid.HexGrid, page PageOverrides) (*grid.HexGrid, error) {
+	settings := hexGrid.Page
+	if page.Size != "" {
+		// Width and height only belong to the custom size
+		settings.Size, settings.Width, settings.Height = page.Size, "", ""
+	}
+	if page.Width != "" {
+		settings.Width = page.Width
+	}
+	if page.Height != "" {
+		settings.Height = page.Height
+	}
+	if page.Orientation != "" {
+		settings.Orientation = page.Orientation
+	}
+	if page.Margin != "" {
+		settings.Margin = page.Margin
+	}
+	if page.HexSize != "" {
+		settings.HexSize = page.HexSize
+	}
+	if page.Fit != nil {
+		settings.Fit = *page.Fit
+	}
+
+	_, err := settings.Layout()
+	if err != nil {
+		return nil, fmt.Errorf("invalid page settings: %w", err)
+	}
+	paged := *hexGrid
+	paged.Page = settings
+	return &paged, nil
+}
+
+// WriteOutputs renders the grid to the output files for the configured format
+func WriteOutputs(config *Config, hexGrid *grid.HexGrid) error {
+	switch config.OutputFormat {
+	case "pdf":
+		// Generate PDF file
+		err := writeOutputFile(config.OutputPath+".pdf", hexGrid, render.PDF)
+		if err != nil {
+			return fmt.Errorf("failed to generate PDF: %w", err)
+		}
+	case "svg", "html":
+		// Generate SVG file
+		err := writeOutputFile(config.OutputPath+".svg", hexGrid, render.SVG)
+		if err != nil {
+			return fmt.Errorf("failed to generate SVG: %w", err)
+		}
+
+		if config.OutputFormat == "html" {
+			// Generate HTML file
+			err = writeOutputFile(config.OutputPath+".html", hexGrid, render.HTML)
+			if err != nil {
+				return fmt.Errorf("failed to generate HTML: %w", err)
+			}
+		}
+	case "png":
+		err := writeOutputFile(config.OutputPath+".png", hexGrid, func(w io.Writer, hexGrid *grid.HexGrid) error {
+			return render.PNG(w, hexGrid, config.Raster)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to generate PNG: %w", err)
+		}
+	case "jpeg":
+		err := writeOutputFile(config.OutputPath+".jpg", hexGrid, func(w io.Writer, hexGrid *grid.HexGrid) error {
+			return render.JPEG(w, hexGrid, config.Raster)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to generate JPEG: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown output format: %s (must be one of %s)", config.OutputFormat, strings.Join(Formats, ", "))
+	}
+
+	return nil
+}
+
+// saveGrid writes the grid data file; it has the signature of a renderer so
+// it can be passed to writeOutputFile
+func saveGrid(w io.Writer, hexGrid *grid.HexGrid) error {
+	return hexGrid.Save(w)
+}
+
+// LoadGrid reads a grid saved by Grid
+func LoadGrid(path string) (*grid.HexGrid, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read grid file: %w", err)
+	}
+	defer file.Close()
+
+	return grid.Load(file)
+}
+
+// writeOutputFile creates the file at path and writes the grid into it
+func writeOutputFile(path string, hexGrid *grid.HexGrid, renderer func(io.Writer, *grid.HexGrid) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Base(path), err)
+	}
+
+	err = renderer(file, hexGrid)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,42 @@
+package generate
+
+import (
+	"testing"
+
+	"hexgrid/grid"
+	"hexgrid/spec"
+)
+
+func TestWithPageSettings(t *testing.T) {
+	config, err := spec.Parse([]byte(`default: "#FFFFFF"
+page: {size: "A3", fit: true}
+items:
+  - {name: "Forest", percentage: 50, style: "fill", color: "#228B22"}`))
+	if err != nil {
+		t.Fatalf("Failed to parse spec: %v", err)
+	}
+	hexGrid := grid.New(3, 3, config)
+
+	noFit := false
+	paged, err := WithPageSettings(hexGrid, PageOverrides{Orientation: spec.OrientationPortrait, Fit: &noFit})
+	if err != nil {
+		t.Fatalf("Failed to apply page settings: %v", err)
+	}
+	want := spec.PageSettings{Size: "A3", Orientation: spec.OrientationPortrait, Fit: false}
+	if paged.Page != want {
+		t.Errorf("Expected page settings %+v, got %+v", want, paged.Page)
+	}
+	// The grid, and the spec saved with it, keep their own settings
+	if hexGrid.Page != config.Page || !hexGrid.Spec().Page.Fit {
+		t.Errorf("Expected the grid's page settings unchanged, got %+v", hexGrid.Page)
+	}
+
+	// Without a Fit override the spec's is kept
+	paged, err = WithPageSettings(hexGrid, PageOverrides{})
+	if err != nil || !paged.Page.Fit {
+		t.Errorf("Expected the spec's fit to be kept, got %+v, %v", paged.Page, err)
+	}
+	if _, err := WithPageSettings(hexGrid, PageOverrides{Size: "B5"}); err == nil {
+		t.Error("Expected an unknown page size to fail")
+	}
+}
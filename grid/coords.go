@@ -0,0 +1,204 @@
+package grid
+
+import (
+	"math"
+
+	"hexgrid/spec"
+)
+
+// Axial is an axial hex coordinate. For flat-top hexes Q counts the visual
+// columns of the grid from left to right and R runs down the grid, sloping
+// up one step every column; for pointy-top hexes R counts the rows and Q
+// runs across them, sloping left one step every row. Either way the six
+// neighbors of a hex are the six axialDirections.
+type Axial struct {
+	Q, R int
+}
+
+// Cube is a cube hex coordinate with X + Y + Z == 0
+type Cube struct {
+	X, Y, Z int
+}
+
+// axialDirections are the offsets to the six neighbors of a hex, going
+// counterclockwise on screen; for flat-top hexes they start at the lower
+// right and for pointy-top hexes at the right
+var axialDirections = [6]Axial{
+	{1, 0}, {1, -1}, {0, -1}, {-1, 0}, {-1, 1}, {0, 1},
+}
+
+// Add returns the sum of two axial coordinates
+func (a Axial) Add(b Axial) Axial {
+	return Axial{a.Q + b.Q, a.R + b.R}
+}
+
+// Scale returns the axial coordinate multiplied by k
+func (a Axial) Scale(k int) Axial {
+	return Axial{a.Q * k, a.R * k}
+}
+
+// Cube converts the axial coordinate to cube coordinates
+func (a Axial) Cube() Cube {
+	return Cube{X: a.Q, Y: -a.Q - a.R, Z: a.R}
+}
+
+// Axial converts the cube coordinate to axial coordinates
+func (c Cube) Axial() Axial {
+	return Axial{Q: c.X, R: c.Z}
+}
+
+// Distance returns the number of hex steps between two axial coordinates
+func (a Axial) Distance(b Axial) int {
+	dq := a.Q - b.Q
+	dr := a.R - b.R
+	return (abs(dq) + abs(dr) + abs(dq+dr)) / 2
+}
+
+// Axial returns the axial coordinate of a cell, which depends on the grid's
+// offset:
+//
+//   - stagger: rows are drawn half a hex apart, with odd rows shifted right
+//     by half a column, so each grid column holds two visual columns:
+//     q = 2*col for even rows and 2*col+1 for odd rows
+//   - odd-q and even-q: q is the column, and odd or even columns are
+//     shifted down by half a hex
+//   - odd-r and even-r: r is the row, and odd or even rows are shifted right
+//     by half a hex
+func (grid *HexGrid) Axial(cell *HexCell) Axial {
+	row, col := cell.Row, cell.Col
+	switch grid.Offset {
+	case spec.OffsetOddQ:
+		return Axial{Q: col, R: row - (col-col&1)/2}
+	case spec.OffsetEvenQ:
+		return Axial{Q: col, R: row - (col+col&1)/2}
+	case spec.OffsetOddR:
+		return Axial{Q: col - (row-row&1)/2, R: row}
+	case spec.OffsetEvenR:
+		return Axial{Q: col - (row+row&1)/2, R: row}
+	}
+	q := 2*col + row&1
+	return Axial{Q: q, R: (row - q) / 2}
+}
+
+// CellAt returns the cell at an axial coordinate, or nil if it is outside the grid
+func (grid *HexGrid) CellAt(a Axial) *HexCell {
+	var row, col int
+	switch grid.Offset {
+	case spec.OffsetOddQ:
+		row, col = a.R+(a.Q-a.Q&1)/2, a.Q
+	case spec.OffsetEvenQ:
+		row, col = a.R+(a.Q+a.Q&1)/2, a.Q
+	case spec.OffsetOddR:
+		row, col = a.R, a.Q+(a.R-a.R&1)/2
+	case spec.OffsetEvenR:
+		row, col = a.R, a.Q+(a.R+a.R&1)/2
+	default:
+		row = 2*a.R + a.Q
+		col = (a.Q - row&1) / 2
+	}
+	if row < 0 || row >= grid.Rows || col < 0 || col >= grid.Cols {
+		return nil
+	}
+	return grid.Cells[row][col]
+}
+
+// Neighbors returns the cells touching cell, in axialDirections order.
+// Cells on the edge of the grid have fewer than six neighbors.
+func (grid *HexGrid) Neighbors(cell *HexCell) []*HexCell {
+	center := grid.Axial(cell)
+	neighbors := make([]*HexCell, 0, 6)
+	for _, direction := range axialDirections {
+		if neighbor := grid.CellAt(center.Add(direction)); neighbor != nil {
+			neighbors = append(neighbors, neighbor)
+		}
+	}
+	return neighbors
+}
+
+// Distance returns the number of hex steps between two cells
+func (grid *HexGrid) Distance(a, b *HexCell) int {
+	return grid.Axial(a).Distance(grid.Axial(b))
+}
+
+// Ring returns the cells exactly radius steps away from center that lie
+// inside the grid. A radius of 0 returns just the center.
+func (grid *HexGrid) Ring(center *HexCell, radius int) []*HexCell {
+	if radius <= 0 {
+		return []*HexCell{center}
+	}
+
+	var cells []*HexCell
+	hex := grid.Axial(center).Add(axialDirections[4].Scale(radius))
+	for _, direction := range axialDirections {
+		for step := 0; step < radius; step++ {
+			if cell := grid.CellAt(hex); cell != nil {
+				cells = append(cells, cell)
+			}
+			hex = hex.Add(direction)
+		}
+	}
+	return cells
+}
+
+// Spiral returns the cells within radius steps of center that lie inside
+// the grid, starting with the center and working outwards ring by ring
+func (grid *HexGrid) Spiral(center *HexCell, radius int) []*HexCell {
+	cells := []*HexCell{center}
+	for r := 1; r <= radius; r++ {
+		cells = append(cells, grid.Ring(center, r)...)
+	}
+	return cells
+}
+
+// Line returns the cells on the straight line from a to b, including both
+// ends. Every cell in the line is a neighbor of the previous one.
+func (grid *HexGrid) Line(a, b *HexCell) []*HexCell {
+	start := grid.Axial(a).Cube()
+	end := grid.Axial(b).Cube()
+	n := grid.Distance(a, b)
+
+	cells := make([]*HexCell, 0, n+1)
+	for i := 0; i <= n; i++ {
+		t := 0.0
+		if n > 0 {
+			t = float64(i) / float64(n)
+		}
+		// Nudge the line off hex edges so ties always round the same way
+		hex := cubeRound(
+			lerp(float64(start.X)+1e-6, float64(end.X)+1e-6, t),
+			lerp(float64(start.Y)+1e-6, float64(end.Y)+1e-6, t),
+			lerp(float64(start.Z)-2e-6, float64(end.Z)-2e-6, t),
+		)
+		if cell := grid.CellAt(hex.Axial()); cell != nil {
+			cells = append(cells, cell)
+		}
+	}
+	return cells
+}
+
+// cubeRound rounds fractional cube coordinates to the nearest hex
+func cubeRound(x, y, z float64) Cube {
+	rx, ry, rz := math.Round(x), math.Round(y), math.Round(z)
+	dx, dy, dz := math.Abs(rx-x), math.Abs(ry-y), math.Abs(rz-z)
+
+	// Fix the component with the largest rounding error so the sum stays zero
+	if dx > dy && dx > dz {
+		rx = -ry - rz
+	} else if dy > dz {
+		ry = -rx - rz
+	} else {
+		rz = -rx - ry
+	}
+	return Cube{X: int(rx), Y: int(ry), Z: int(rz)}
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,229 @@
+package grid_test
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"hexgrid/grid"
+	"hexgrid/spec"
+)
+
+// layouts are the hex layouts the coordinate tests run on, with the number
+// of neighbors of the top left cell in each
+var layouts = []struct {
+	layout          spec.HexLayout
+	cornerNeighbors int
+}{
+	{spec.HexLayout{}, 2},
+	{spec.HexLayout{Offset: spec.OffsetOddQ}, 2},
+	{spec.HexLayout{Offset: spec.OffsetEvenQ}, 3},
+	{spec.HexLayout{Orientation: spec.OrientationPointy, Offset: spec.OffsetOddR}, 2},
+	{spec.HexLayout{Orientation: spec.OrientationPointy, Offset: spec.OffsetEvenR}, 3},
+}
+
+// renderedGrid returns an empty grid; its cell centers are the ones every renderer draws
+func renderedGrid(t *testing.T, rows, cols int, layout spec.HexLayout) *grid.HexGrid {
+	t.Helper()
+	return grid.New(rows, cols, &spec.Spec{Default: "#FFFFFF", Layout: layout})
+}
+
+func TestAxialRoundTrip(t *testing.T) {
+	for _, tt := range layouts {
+		g := renderedGrid(t, 9, 5, tt.layout)
+		for row := 0; row < g.Rows; row++ {
+			for col := 0; col < g.Cols; col++ {
+				cell := g.Cells[row][col]
+				if got := g.CellAt(g.Axial(cell)); got != cell {
+					t.Errorf("CellAt(Axial(%d,%d)) returned the wrong cell", row, col)
+				}
+				if a := g.Axial(cell); a.Cube().Axial() != a {
+					t.Errorf("Cube round trip failed for %v", a)
+				}
+			}
+		}
+		if g.CellAt(grid.Axial{Q: -1, R: 0}) != nil || g.CellAt(grid.Axial{Q: 0, R: 100}) != nil {
+			t.Error("Expected nil for coordinates outside the grid")
+		}
+	}
+}
+
+func TestNeighborsMatchRenderedGeometry(t *testing.T) {
+	for _, tt := range layouts {
+		g := renderedGrid(t, 12, 6, tt.layout)
+
+		centerDistance := func(a, b *grid.HexCell) float64 {
+			return math.Hypot(a.X-b.X, a.Y-b.Y)
+		}
+
+		for row := 2; row < g.Rows-2; row++ {
+			for col := 1; col < g.Cols-1; col++ {
+				cell := g.Cells[row][col]
+
+				// The six closest rendered cells must be exactly the neighbors
+				var others []*grid.HexCell
+				for _, cells := range g.Cells {
+					for _, other := range cells {
+						if other != cell {
+							others = append(others, other)
+						}
+					}
+				}
+				sort.Slice(others, func(i, j int) bool {
+					return centerDistance(cell, others[i]) < centerDistance(cell, others[j])
+				})
+
+				neighbors := g.Neighbors(cell)
+				if len(neighbors) != 6 {
+					t.Fatalf("Expected 6 neighbors for interior cell %d,%d, got %d", row, col, len(neighbors))
+				}
+				isNeighbor := make(map[*grid.HexCell]bool)
+				for _, neighbor := range neighbors {
+					isNeighbor[neighbor] = true
+					if g.Distance(cell, neighbor) != 1 {
+						t.Errorf("Expected distance 1 to neighbor of %d,%d", row, col)
+					}
+				}
+				for _, closest := range others[:6] {
+					if !isNeighbor[closest] {
+						t.Errorf("Cell %d,%d is drawn next to %d,%d but they are not neighbors",
+							row, col, closest.Row, closest.Col)
+					}
+				}
+			}
+		}
+
+		// Corner cells only have the neighbors inside the grid
+		if n := len(g.Neighbors(g.Cells[0][0])); n != tt.cornerNeighbors {
+			t.Errorf("%v: expected %d neighbors for the top left cell, got %d", tt.layout, tt.cornerNeighbors, n)
+		}
+	}
+}
+
+func TestDistanceMatchesNeighborSteps(t *testing.T) {
+	for _, tt := range layouts {
+		g := renderedGrid(t, 10, 4, tt.layout)
+		start := g.Cells[3][1]
+
+		// Breadth-first search over Neighbors gives the true step count
+		steps := map[*grid.HexCell]int{start: 0}
+		queue := []*grid.HexCell{start}
+		for len(queue) > 0 {
+			cell := queue[0]
+			queue = queue[1:]
+			for _, neighbor := range g.Neighbors(cell) {
+				if _, seen := steps[neighbor]; !seen {
+					steps[neighbor] = steps[cell] + 1
+					queue = append(queue, neighbor)
+				}
+			}
+		}
+
+		for cell, want := range steps {
+			if got := g.Distance(start, cell); got != want {
+				t.Errorf("Distance to %d,%d: expected %d, got %d", cell.Row, cell.Col, want, got)
+			}
+		}
+	}
+}
+
+func TestRingSpiralAndLine(t *testing.T) {
+	for _, tt := range layouts {
+		g := renderedGrid(t, 20, 8, tt.layout)
+		center := g.Cells[10][4]
+
+		for radius := 0; radius <= 3; radius++ {
+			ring := g.Ring(center, radius)
+			want := 6 * radius
+			if radius == 0 {
+				want = 1
+			}
+			if len(ring) != want {
+				t.Errorf("Expected %d cells in ring %d, got %d", want, radius, len(ring))
+			}
+			for _, cell := range ring {
+				if d := g.Distance(center, cell); d != radius {
+					t.Errorf("Cell in ring %d is at distance %d", radius, d)
+				}
+			}
+		}
+
+		if spiral := g.Spiral(center, 2); len(spiral) != 19 {
+			t.Errorf("Expected 19 cells in spiral of radius 2, got %d", len(spiral))
+		}
+
+		// Rings are clipped to the grid
+		if ring := g.Ring(g.Cells[0][0], 1); len(ring) != tt.cornerNeighbors {
+			t.Errorf("%v: expected %d cells in the clipped ring, got %d", tt.layout, tt.cornerNeighbors, len(ring))
+		}
+
+		a, b := g.Cells[1][0], g.Cells[18][7]
+		line := g.Line(a, b)
+		if len(line) != g.Distance(a, b)+1 {
+			t.Fatalf("Expected %d cells in line, got %d", g.Distance(a, b)+1, len(line))
+		}
+		if line[0] != a || line[len(line)-1] != b {
+			t.Error("Expected line to start and end at its endpoints")
+		}
+		for i := 1; i < len(line); i++ {
+			if g.Distance(line[i-1], line[i]) != 1 {
+				t.Errorf("Line step %d is not between neighbors", i)
+			}
+		}
+	}
+}
+
+func TestLayout(t *testing.T) {
+	for _, tt := range layouts {
+		g := renderedGrid(t, 7, 4, tt.layout)
+		layout := g.Layout(10)
+
+		// Neighboring hexagons touch along a whole side
+		for _, cells := range g.Cells {
+			for _, cell := range cells {
+				center := layout.Center(cell)
+				for _, neighbor := range g.Neighbors(cell) {
+					other := layout.Center(neighbor)
+					if d := math.Hypot(center.X-other.X, center.Y-other.Y); math.Abs(d-10*math.Sqrt(3)) > 1e-9 {
+						t.Fatalf("Cells %d,%d and %d,%d are %g apart", cell.Row, cell.Col, neighbor.Row, neighbor.Col, d)
+					}
+				}
+			}
+		}
+
+		// The map's bounds just fit every corner
+		minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+		for _, cells := range g.Cells {
+			for _, cell := range cells {
+				for _, corner := range layout.Corners(cell) {
+					minX, minY = math.Min(minX, corner.X), math.Min(minY, corner.Y)
+					maxX, maxY = math.Max(maxX, corner.X), math.Max(maxY, corner.Y)
+				}
+			}
+		}
+		if math.Abs(minX) > 1e-9 || math.Abs(minY) > 1e-9 || math.Abs(maxX-layout.Width) > 1e-9 || math.Abs(maxY-layout.Height) > 1e-9 {
+			t.Errorf("Expected corners to span 0,0 to %g,%g, got %g,%g to %g,%g", layout.Width, layout.Height, minX, minY, maxX, maxY)
+		}
+
+		// Points find the hexagon they are in, up to just inside its corners
+		for _, cells := range g.Cells {
+			for _, cell := range cells {
+				center := layout.Center(cell)
+				points := []grid.Point{center}
+				for _, corner := range layout.Corners(cell) {
+					points = append(points, grid.Point{X: center.X + 0.95*(corner.X-center.X), Y: center.Y + 0.95*(corner.Y-center.Y)})
+				}
+				for _, p := range points {
+					if found := g.CellAtPoint(layout, p); found != cell {
+						t.Fatalf("%v: expected %g,%g in cell %d,%d, got %v", tt.layout, p.X, p.Y, cell.Row, cell.Col, found)
+					}
+				}
+			}
+		}
+		for _, p := range []grid.Point{{X: -1, Y: -1}, {X: layout.Width + 1, Y: layout.Height / 2}, {X: 0, Y: 0}} {
+			if found := g.CellAtPoint(layout, p); found != nil {
+				t.Errorf("%v: expected %g,%g outside the map, got cell %d,%d", tt.layout, p.X, p.Y, found.Row, found.Col)
+			}
+		}
+	}
+}
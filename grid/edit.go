@@ -0,0 +1,106 @@
+package grid
+
+import (
+	"fmt"
+	"math/rand"
+
+	"hexgrid/dice"
+	"hexgrid/spec"
+)
+
+// CellContent is the part of a cell that can be edited by hand
+type CellContent struct {
+	ItemType   *spec.ItemType
+	DiceResult *dice.Result
+}
+
+// Content returns the cell's item and dice result
+func (cell *HexCell) Content() CellContent {
+	return CellContent{ItemType: cell.ItemType, DiceResult: cell.DiceResult}
+}
+
+// ItemContent returns the content of a cell holding itemType, with its dice
+// rolled using rng. A nil itemType is an empty cell.
+func ItemContent(itemType *spec.ItemType, rng *rand.Rand) (CellContent, error) {
+	content := CellContent{ItemType: itemType}
+	if itemType == nil || itemType.Dice == "" {
+		return content, nil
+	}
+
+	result, err := dice.Roll(rng, itemType.Dice)
+	if err != nil {
+		return CellContent{}, fmt.Errorf("invalid dice for item %s: %w", itemType.Name, err)
+	}
+	content.DiceResult = result
+	return content, nil
+}
+
+// DiceContent returns the content of a cell holding itemType with its dice
+// result set to total, which must be a total the item's dice can roll
+func DiceContent(itemType *spec.ItemType, total int) (CellContent, error) {
+	if itemType == nil || itemType.Dice == "" {
+		return CellContent{}, fmt.Errorf("only items with dice have a dice result")
+	}
+	expr, err := dice.Parse(itemType.Dice)
+	if err != nil {
+		return CellContent{}, fmt.Errorf("invalid dice for item %s: %w", itemType.Name, err)
+	}
+	if total < expr.Min() || total > expr.Max() {
+		return CellContent{}, fmt.Errorf("%s can't roll %d (must be %d to %d)", itemType.Dice, total, expr.Min(), expr.Max())
+	}
+
+	return CellContent{
+		ItemType:   itemType,
+		DiceResult: &dice.Result{Total: total, Breakdown: "set by hand"},
+	}, nil
+}
+
+// History changes the contents of cells and records every change so it can
+// be undone and redone
+type History struct {
+	done   []cellEdit // Changes in the order they were made
+	undone []cellEdit // Undone changes, the most recently undone last
+}
+
+// cellEdit is a change to one cell
+type cellEdit struct {
+	cell          *HexCell
+	before, after CellContent
+}
+
+// Set changes the content of cell. It can't be redone after that.
+func (h *History) Set(cell *HexCell, content CellContent) {
+	h.done = append(h.done, cellEdit{cell: cell, before: cell.Content(), after: content})
+	h.undone = nil
+	cell.ItemType, cell.DiceResult = content.ItemType, content.DiceResult
+}
+
+// Undo reverts the most recent change and returns its cell, or returns nil
+// if there is nothing to undo
+func (h *History) Undo() *HexCell {
+	if len(h.done) == 0 {
+		return nil
+	}
+	edit := h.done[len(h.done)-1]
+	h.done = h.done[:len(h.done)-1]
+	h.undone = append(h.undone, edit)
+	edit.cell.ItemType, edit.cell.DiceResult = edit.before.ItemType, edit.before.DiceResult
+	return edit.cell
+}
+
+// Redo makes the most recently undone change again and returns its cell,
+// or returns nil if there is nothing to redo
+func (h *History) Redo() *HexCell {
+	if len(h.undone) == 0 {
+		return nil
+	}
+	edit := h.undone[len(h.undone)-1]
+	h.undone = h.undone[:len(h.undone)-1]
+	h.done = append(h.done, edit)
+	edit.cell.ItemType, edit.cell.DiceResult = edit.after.ItemType, edit.after.DiceResult
+	return edit.cell
+}
+
+// CanUndo and CanRedo report whether there are changes to undo or redo
+func (h *History) CanUndo() bool { return len(h.done) > 0 }
+func (h *History) CanRedo() bool { return len(h.undone) > 0 }
@@ -0,0 +1,89 @@
+package grid
+
+import (
+	"math/rand"
+	"testing"
+
+	"hexgrid/spec"
+)
+
+func TestHistory(t *testing.T) {
+	config := &spec.Spec{
+		Default: "#FFFFFF",
+		Items: []spec.ItemType{
+			{Name: "Forest", Percentage: 50, Style: "fill", Color: "#228B22"},
+			{Name: "Village", Percentage: 50, Style: "dot", Color: "#FFD700", Dice: "2d6"},
+		},
+	}
+	grid := New(2, 2, config)
+	forest, village := grid.ItemTypes[0], grid.ItemTypes[1]
+	cell := grid.Cells[0][1]
+	rng := rand.New(rand.NewSource(1))
+
+	var history History
+	if history.CanUndo() || history.CanRedo() || history.Undo() != nil || history.Redo() != nil {
+		t.Fatal("Expected an empty history to have nothing to undo or redo")
+	}
+
+	// Place a village, then set its dice, then clear it
+	placed, err := ItemContent(village, rng)
+	if err != nil {
+		t.Fatalf("Failed to place village: %v", err)
+	}
+	if placed.DiceResult == nil || placed.DiceResult.Total < 2 || placed.DiceResult.Total > 12 {
+		t.Fatalf("Expected a village to roll 2d6, got %+v", placed.DiceResult)
+	}
+	history.Set(cell, placed)
+
+	set, err := DiceContent(village, 12)
+	if err != nil {
+		t.Fatalf("Failed to set dice: %v", err)
+	}
+	history.Set(cell, set)
+	if cell.ItemType != village || cell.DiceResult.Total != 12 {
+		t.Fatalf("Expected a village with 12, got %+v", cell.Content())
+	}
+	history.Set(cell, CellContent{})
+
+	// Undo walks back through every state and redo forward again
+	states := []CellContent{{}, placed, set, {}}
+	for i := len(states) - 2; i >= 0; i-- {
+		if history.Undo() != cell {
+			t.Fatalf("Expected undo to change the cell")
+		}
+		if cell.Content() != states[i] {
+			t.Errorf("After undo expected %+v, got %+v", states[i], cell.Content())
+		}
+	}
+	if history.CanUndo() {
+		t.Error("Expected nothing left to undo")
+	}
+	for i := 1; i < len(states); i++ {
+		if history.Redo() != cell {
+			t.Fatalf("Expected redo to change the cell")
+		}
+		if cell.Content() != states[i] {
+			t.Errorf("After redo expected %+v, got %+v", states[i], cell.Content())
+		}
+	}
+
+	// A new change drops the changes that were undone
+	history.Undo()
+	forestContent, err := ItemContent(forest, rng)
+	if err != nil || forestContent.DiceResult != nil {
+		t.Fatalf("Expected a forest without dice, got %+v, %v", forestContent, err)
+	}
+	history.Set(cell, forestContent)
+	if history.CanRedo() {
+		t.Error("Expected nothing to redo after a new change")
+	}
+
+	for _, tt := range []struct {
+		itemType *spec.ItemType
+		total    int
+	}{{village, 1}, {village, 13}, {forest, 5}, {nil, 5}} {
+		if _, err := DiceContent(tt.itemType, tt.total); err == nil {
+			t.Errorf("Expected setting %d on %v to fail", tt.total, tt.itemType)
+		}
+	}
+}
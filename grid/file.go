@@ -0,0 +1,144 @@
+package grid
+
+import (
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+
+	"hexgrid/dice"
+	"hexgrid/spec"
+)
+
+// fileVersion is the version of the grid file format written by Save
+const fileVersion = 1
+
+// gridFile is the YAML form of a HexGrid. The spec, including the seed the
+// grid was populated with, is stored in full so the grid can be rendered
+// without the original spec file.
+type gridFile struct {
+	Version int        `yaml:"version"`
+	Rows    int        `yaml:"rows"`
+	Cols    int        `yaml:"cols"`
+	Spec    yaml.Node  `yaml:"spec"`
+	Cells   []cellFile `yaml:"cells"`
+}
+
+// cellFile is the YAML form of a filled HexCell; empty cells are left out
+type cellFile struct {
+	Row       int          `yaml:"row"`
+	Col       int          `yaml:"col"`
+	Label     string       `yaml:"label"` // The cell's identifier, as printed on the map; not read back
+	Item      string       `yaml:"item"`
+	Dice      *dice.Result `yaml:"dice,omitempty"`
+	Elevation float64      `yaml:"elevation,omitempty"`
+	Moisture  float64      `yaml:"moisture,omitempty"`
+}
+
+// Spec returns a spec describing the grid's item types and settings
+func (grid *HexGrid) Spec() *spec.Spec {
+	config := &spec.Spec{
+		Default:    grid.DefaultColor,
+		Seed:       grid.Seed,
+		Generator:  grid.Generator,
+		Noise:      grid.Noise,
+		Occurrence: grid.Occurrence,
+		Layout:     spec.HexLayout{Orientation: grid.Orientation, Offset: grid.Offset},
+		Labels:     grid.Labels,
+		Page:       grid.Page,
+		Items:      make([]spec.ItemType, len(grid.ItemTypes)),
+	}
+	for i, itemType := range grid.ItemTypes {
+		config.Items[i] = *itemType
+	}
+	return config
+}
+
+// Save writes the grid, including its spec and every cell's item and dice
+// result, to w as YAML that Load can read back
+func (grid *HexGrid) Save(w io.Writer) error {
+	file := gridFile{
+		Version: fileVersion,
+		Rows:    grid.Rows,
+		Cols:    grid.Cols,
+	}
+	err := file.Spec.Encode(grid.Spec())
+	if err != nil {
+		return fmt.Errorf("failed to encode spec: %w", err)
+	}
+
+	for _, row := range grid.Cells {
+		for _, cell := range row {
+			if cell.ItemType == nil {
+				continue
+			}
+			file.Cells = append(file.Cells, cellFile{
+				Row:       cell.Row,
+				Col:       cell.Col,
+				Label:     grid.Label(cell),
+				Item:      cell.ItemType.Name,
+				Dice:      cell.DiceResult,
+				Elevation: cell.Elevation,
+				Moisture:  cell.Moisture,
+			})
+		}
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	err = encoder.Encode(&file)
+	if err != nil {
+		return fmt.Errorf("failed to write grid: %w", err)
+	}
+	return encoder.Close()
+}
+
+// Load reads a grid written by Save, validating its spec
+func Load(r io.Reader) (*HexGrid, error) {
+	var file gridFile
+	err := yaml.NewDecoder(r).Decode(&file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse grid file: %w", err)
+	}
+	if file.Version != fileVersion {
+		return nil, fmt.Errorf("unsupported grid file version: %d", file.Version)
+	}
+	if file.Rows <= 0 || file.Cols <= 0 {
+		return nil, fmt.Errorf("invalid grid size: %dx%d", file.Rows, file.Cols)
+	}
+
+	// Run the stored spec through the same validation as a spec file
+	specData, err := yaml.Marshal(&file.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec from grid file: %w", err)
+	}
+	config, err := spec.Parse(specData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid spec in grid file: %w", err)
+	}
+
+	grid := New(file.Rows, file.Cols, config)
+
+	itemTypes := make(map[string]*spec.ItemType, len(grid.ItemTypes))
+	for _, itemType := range grid.ItemTypes {
+		itemTypes[itemType.Name] = itemType
+	}
+
+	for _, c := range file.Cells {
+		if c.Row < 0 || c.Row >= grid.Rows || c.Col < 0 || c.Col >= grid.Cols {
+			return nil, fmt.Errorf("cell %d,%d is outside the %dx%d grid", c.Row, c.Col, grid.Rows, grid.Cols)
+		}
+		itemType := itemTypes[c.Item]
+		if itemType == nil {
+			return nil, fmt.Errorf("cell %d,%d has unknown item: %s", c.Row, c.Col, c.Item)
+		}
+
+		cell := grid.Cells[c.Row][c.Col]
+		cell.ItemType = itemType
+		cell.DiceResult = c.Dice
+		cell.Elevation = c.Elevation
+		cell.Moisture = c.Moisture
+	}
+
+	return grid, nil
+}
@@ -0,0 +1,207 @@
+// Package grid holds the hex grid model and populates grids from a spec.
+package grid
+
+import (
+	"fmt"
+	"math/rand"
+
+	"hexgrid/dice"
+	"hexgrid/spec"
+)
+
+// HexCell represents a single hexagon cell in the grid
+type HexCell struct {
+	Row        int
+	Col        int
+	ItemType   *spec.ItemType
+	X, Y       float64      // Center for hexagons of size 1; see Layout
+	DiceResult *dice.Result // Dice roll result if item has dice
+	Elevation  float64      // Normalized elevation from 0 to 1 (noise generator only)
+	Moisture   float64      // Normalized moisture from 0 to 1 (noise generator only)
+}
+
+// HexGrid represents the complete hex grid
+type HexGrid struct {
+	Rows         int
+	Cols         int
+	Cells        [][]*HexCell
+	ItemTypes    []*spec.ItemType
+	DefaultColor string
+	Seed         int64                   // Seed used to populate the grid
+	Generator    string                  // How Populate assigns items; see spec.Spec.Generator
+	Noise        spec.NoiseSettings      // Settings for the noise generator
+	Occurrence   spec.OccurrenceSettings // Settings for the occurrence generator
+	Orientation  string                  // spec.OrientationFlat or spec.OrientationPointy
+	Offset       string                  // How rows or columns are shifted; one of the spec.Offset constants
+	Labels       spec.LabelSettings      // Coordinate labels drawn on the hexes
+	Page         spec.PageSettings       // Page setup for PDF output
+
+	diceExprs     map[*spec.ItemType]*dice.Expr // Parsed dice of the item types, set by Populate
+	countExprs    map[*spec.ItemType]*dice.Expr // Parsed counts of the item types, set by Populate
+	width, height float64                       // Size of the map for hexagons of size 1
+}
+
+// New creates a new, empty hex grid with the specified dimensions
+func New(rows, cols int, config *spec.Spec) *HexGrid {
+	grid := &HexGrid{
+		Rows:         rows,
+		Cols:         cols,
+		Cells:        make([][]*HexCell, rows),
+		ItemTypes:    make([]*spec.ItemType, len(config.Items)),
+		DefaultColor: config.Default,
+		Seed:         config.Seed,
+		Generator:    config.Generator,
+		Noise:        config.Noise,
+		Occurrence:   config.Occurrence,
+		Labels:       config.Labels,
+		Page:         config.Page,
+	}
+	grid.Orientation, grid.Offset = config.Layout.Resolve()
+
+	// Copy item types
+	for i := range config.Items {
+		grid.ItemTypes[i] = &config.Items[i]
+	}
+
+	// Initialize cells
+	for row := 0; row < rows; row++ {
+		grid.Cells[row] = make([]*HexCell, cols)
+		for col := 0; col < cols; col++ {
+			grid.Cells[row][col] = &HexCell{
+				Row: row,
+				Col: col,
+			}
+		}
+	}
+	grid.placeCells()
+
+	return grid
+}
+
+// NewSeed returns a random non-zero seed for grids that don't specify one
+func NewSeed() int64 {
+	for {
+		if seed := rand.Int63(); seed != 0 {
+			return seed
+		}
+	}
+}
+
+// Populate fills the grid with items using the grid's generator and then
+// rearranges them to satisfy the items' placement rules, returning a
+// *RuleError if that is not possible. All randomness comes from rng, so the
+// same seed always produces the same grid.
+func (grid *HexGrid) Populate(rng *rand.Rand) error {
+	// Parse the dice up front; specs loaded by the spec package have
+	// already been checked, so this only fails for hand-built specs
+	grid.diceExprs = make(map[*spec.ItemType]*dice.Expr)
+	grid.countExprs = make(map[*spec.ItemType]*dice.Expr)
+	for _, itemType := range grid.ItemTypes {
+		if itemType.Dice != "" {
+			expr, err := dice.Parse(itemType.Dice)
+			if err != nil {
+				return fmt.Errorf("invalid dice for item %s: %w", itemType.Name, err)
+			}
+			grid.diceExprs[itemType] = expr
+		}
+		if itemType.Count != "" {
+			expr, err := dice.Parse(itemType.Count)
+			if err != nil {
+				return fmt.Errorf("invalid count for item %s: %w", itemType.Name, err)
+			}
+			grid.countExprs[itemType] = expr
+		}
+	}
+
+	switch grid.Generator {
+	case spec.GeneratorNoise:
+		grid.populateNoise(rng)
+	case spec.GeneratorOccurrence:
+		if err := grid.populateOccurrence(rng); err != nil {
+			return err
+		}
+	default:
+		grid.populateShuffle(rng)
+	}
+
+	return grid.enforceRules(rng)
+}
+
+// populateShuffle fills the grid with items based on their quantities.
+// Items given by percentage, count or range get their number of cells first,
+// in spec order while cells last, and items with a chance roll for the
+// cells left over.
+func (grid *HexGrid) populateShuffle(rng *rand.Rand) {
+	totalCells := grid.Rows * grid.Cols
+	counts := grid.itemCounts(rng)
+
+	// Create a list of all cells
+	allCells := make([]*HexCell, 0, totalCells)
+	for row := 0; row < grid.Rows; row++ {
+		for col := 0; col < grid.Cols; col++ {
+			allCells = append(allCells, grid.Cells[row][col])
+		}
+	}
+
+	// Grow the clustered items first so their regions aren't broken up
+	// by scattered items, picking cluster seeds from a shuffled cell order
+	var clustered, scattered, byChance []*spec.ItemType
+	for _, itemType := range grid.ItemTypes {
+		switch {
+		case itemType.Quantity() == spec.QuantityChance:
+			byChance = append(byChance, itemType)
+		case itemType.Placement == spec.PlacementClustered:
+			clustered = append(clustered, itemType)
+		default:
+			scattered = append(scattered, itemType)
+		}
+	}
+	if len(clustered) > 0 {
+		seeds := make([]*HexCell, len(allCells))
+		copy(seeds, allCells)
+		rng.Shuffle(len(seeds), func(i, j int) {
+			seeds[i], seeds[j] = seeds[j], seeds[i]
+		})
+		for _, itemType := range clustered {
+			grid.placeClustered(rng, itemType, counts[itemType], &seeds)
+		}
+
+		// Only the cells left empty are available to scattered items
+		freeCells := allCells[:0]
+		for _, cell := range allCells {
+			if cell.ItemType == nil {
+				freeCells = append(freeCells, cell)
+			}
+		}
+		allCells = freeCells
+	}
+
+	// Shuffle the cells
+	rng.Shuffle(len(allCells), func(i, j int) {
+		allCells[i], allCells[j] = allCells[j], allCells[i]
+	})
+
+	// Assign items to cells in spec order, with each item type occupying
+	// its share of the cells
+	cellIndex := 0
+	for _, itemType := range scattered {
+		for i := 0; i < counts[itemType] && cellIndex < len(allCells); i++ {
+			grid.place(rng, allCells[cellIndex], itemType)
+			cellIndex++
+		}
+	}
+
+	if len(byChance) > 0 {
+		grid.placeByChance(rng, byChance, allCells[cellIndex:])
+	}
+}
+
+// place puts an item in a cell, rolling its dice if it has any
+func (grid *HexGrid) place(rng *rand.Rand, cell *HexCell, itemType *spec.ItemType) {
+	cell.ItemType = itemType
+
+	// Roll dice if the item has a dice expression
+	if expr := grid.diceExprs[itemType]; expr != nil {
+		cell.DiceResult = expr.Roll(rng)
+	}
+}
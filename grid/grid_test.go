@@ -0,0 +1,530 @@
+package grid
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"hexgrid/spec"
+)
+
+func TestPopulateDeterministic(t *testing.T) {
+	config := &spec.Spec{
+		Default: "#FFFFFF",
+		Items: []spec.ItemType{
+			{Name: "Forest", Percentage: 40, Style: "fill", Color: "#228B22"},
+			{Name: "Water", Percentage: 20, Style: "fill", Color: "#4169E1"},
+			{Name: "Village", Percentage: 10, Style: "dot", Color: "#FFD700", Dice: "2d6"},
+		},
+	}
+
+	generate := func(seed int64) *HexGrid {
+		grid := New(12, 8, config)
+		if err := grid.Populate(rand.New(rand.NewSource(seed))); err != nil {
+			t.Fatalf("Failed to populate grid: %v", err)
+		}
+		return grid
+	}
+
+	first, second := generate(42), generate(42)
+	for row := 0; row < first.Rows; row++ {
+		for col := 0; col < first.Cols; col++ {
+			a, b := first.Cells[row][col], second.Cells[row][col]
+			if a.ItemType != nil && b.ItemType != nil {
+				if a.ItemType.Name != b.ItemType.Name {
+					t.Fatalf("Cell %d,%d differs between runs: %s vs %s", row, col, a.ItemType.Name, b.ItemType.Name)
+				}
+			} else if a.ItemType != b.ItemType {
+				t.Fatalf("Cell %d,%d differs between runs", row, col)
+			}
+			if (a.DiceResult == nil) != (b.DiceResult == nil) ||
+				(a.DiceResult != nil && a.DiceResult.Total != b.DiceResult.Total) {
+				t.Fatalf("Dice result for cell %d,%d differs between runs", row, col)
+			}
+		}
+	}
+}
+
+func TestPopulateClustered(t *testing.T) {
+	config := &spec.Spec{
+		Default: "#FFFFFF",
+		Items: []spec.ItemType{
+			{Name: "Water", Percentage: 10, Style: "fill", Color: "#4169E1"},
+			{Name: "Forest", Percentage: 30, Style: "fill", Color: "#228B22", Placement: spec.PlacementClustered, Cohesion: 0.7},
+		},
+	}
+
+	grid := New(20, 10, config)
+	if err := grid.Populate(rand.New(rand.NewSource(7))); err != nil {
+		t.Fatalf("Failed to populate grid: %v", err)
+	}
+
+	counts := make(map[string]int)
+	var forest []*HexCell
+	for _, cells := range grid.Cells {
+		for _, cell := range cells {
+			if cell.ItemType != nil {
+				counts[cell.ItemType.Name]++
+				if cell.ItemType.Name == "Forest" {
+					forest = append(forest, cell)
+				}
+			}
+		}
+	}
+	if counts["Forest"] != 60 || counts["Water"] != 20 {
+		t.Errorf("Expected 60 Forest and 20 Water cells, got %v", counts)
+	}
+
+	// Without a cluster size the forest grows as a single region
+	seen := map[*HexCell]bool{forest[0]: true}
+	queue := []*HexCell{forest[0]}
+	for len(queue) > 0 {
+		cell := queue[0]
+		queue = queue[1:]
+		for _, neighbor := range grid.Neighbors(cell) {
+			if neighbor.ItemType == cell.ItemType && !seen[neighbor] {
+				seen[neighbor] = true
+				queue = append(queue, neighbor)
+			}
+		}
+	}
+	if len(seen) != len(forest) {
+		t.Errorf("Expected one contiguous forest of %d cells, largest region has %d", len(forest), len(seen))
+	}
+}
+
+func TestPopulateNoise(t *testing.T) {
+	config := &spec.Spec{
+		Default:   "#FFFFFF",
+		Generator: spec.GeneratorNoise,
+		Items: []spec.ItemType{
+			{Name: "Water", Style: "fill", Color: "#4169E1", Elevation: spec.Band{0, 0.3}},
+			{Name: "Land", Style: "fill", Color: "#228B22", Elevation: spec.Band{0.3, 1}},
+		},
+	}
+
+	grid := New(20, 10, config)
+	if err := grid.Populate(rand.New(rand.NewSource(3))); err != nil {
+		t.Fatalf("Failed to populate grid: %v", err)
+	}
+
+	water, sameNeighbors, allNeighbors := 0, 0, 0
+	for _, cells := range grid.Cells {
+		for _, cell := range cells {
+			if cell.ItemType == nil {
+				t.Fatalf("Expected every cell to be filled, %d,%d is empty", cell.Row, cell.Col)
+			}
+			if cell.Elevation < 0 || cell.Elevation > 1 {
+				t.Errorf("Elevation %f out of range for %d,%d", cell.Elevation, cell.Row, cell.Col)
+			}
+			if cell.ItemType.Name != "Water" {
+				continue
+			}
+			water++
+			for _, neighbor := range grid.Neighbors(cell) {
+				allNeighbors++
+				if neighbor.ItemType == cell.ItemType {
+					sameNeighbors++
+				}
+			}
+		}
+	}
+
+	// Elevations are normalized by rank, so the band covers the lowest 30%
+	if water != 60 {
+		t.Errorf("Expected 60 Water cells, got %d", water)
+	}
+
+	// Coherent noise puts water next to water far more often than the 30% of a random shuffle
+	if ratio := float64(sameNeighbors) / float64(allNeighbors); ratio < 0.5 {
+		t.Errorf("Expected water to form regions, only %.0f%% of water neighbors are water", ratio*100)
+	}
+}
+
+func TestPopulateQuantities(t *testing.T) {
+	countItems := func(grid *HexGrid) map[string]int {
+		counts := make(map[string]int)
+		for _, cells := range grid.Cells {
+			for _, cell := range cells {
+				if cell.ItemType != nil {
+					counts[cell.ItemType.Name]++
+				}
+			}
+		}
+		return counts
+	}
+
+	// Shares of 24.5, 14.7 and 9.8 cells round to a full grid of 49
+	config := &spec.Spec{
+		Default: "#FFFFFF",
+		Items: []spec.ItemType{
+			{Name: "Plains", Percentage: 50, Style: "fill", Color: "#90EE90"},
+			{Name: "Forest", Percentage: 30, Style: "fill", Color: "#228B22"},
+			{Name: "Hills", Percentage: 20, Style: "fill", Color: "#8B4513"},
+		},
+	}
+	grid := New(7, 7, config)
+	if err := grid.Populate(rand.New(rand.NewSource(1))); err != nil {
+		t.Fatalf("Failed to populate grid: %v", err)
+	}
+	if counts, want := countItems(grid), map[string]int{"Plains": 24, "Forest": 15, "Hills": 10}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("Expected largest remainder counts %v, got %v", want, counts)
+	}
+
+	config = &spec.Spec{
+		Default: "#000000",
+		Items: []spec.ItemType{
+			{Name: "Hypergiant", Percentage: 0.147, Min: 1, Style: "dot", Color: "#FFFFFF"},
+			{Name: "Station", Count: "3", Style: "dot", Color: "#C0C0C0"},
+			{Name: "Anomaly", Count: "1d3", Style: "dot", Color: "#FF00FF"},
+			{Name: "Nebula", Min: 2, Max: 4, Style: "fill", Color: "#800080"},
+			{Name: "Capped", Percentage: 50, Max: 5, Style: "fill", Color: "#FF0000"},
+		},
+	}
+	anomalies := make(map[int]bool)
+	for seed := int64(1); seed <= 30; seed++ {
+		grid := New(10, 10, config)
+		if err := grid.Populate(rand.New(rand.NewSource(seed))); err != nil {
+			t.Fatalf("Failed to populate grid: %v", err)
+		}
+		counts := countItems(grid)
+		if counts["Hypergiant"] != 1 || counts["Station"] != 3 || counts["Capped"] != 5 {
+			t.Errorf("Seed %d: expected 1 hypergiant, 3 stations and 5 capped, got %v", seed, counts)
+		}
+		if n := counts["Nebula"]; n < 2 || n > 4 {
+			t.Errorf("Seed %d: expected 2 to 4 nebulas, got %d", seed, n)
+		}
+		if n := counts["Anomaly"]; n < 1 || n > 3 {
+			t.Errorf("Seed %d: expected 1 to 3 anomalies, got %d", seed, n)
+		}
+		anomalies[counts["Anomaly"]] = true
+	}
+	if len(anomalies) != 3 {
+		t.Errorf("Expected every count of anomalies from 1d3, got %v", anomalies)
+	}
+
+	// Chances are rolled for every cell left over, then bounded
+	config = &spec.Spec{
+		Default: "#000000",
+		Items: []spec.ItemType{
+			{Name: "Star", Chance: 30, Style: "dot", Color: "#FFFF00"},
+			{Name: "Rare", Chance: 0.01, Min: 3, Style: "dot", Color: "#FFFFFF"},
+			{Name: "Comet", Chance: 50, Max: 10, Style: "dot", Color: "#00FFFF"},
+			{Name: "Base", Count: "20", Style: "dot", Color: "#C0C0C0"},
+		},
+	}
+	grid = New(20, 20, config)
+	if err := grid.Populate(rand.New(rand.NewSource(4))); err != nil {
+		t.Fatalf("Failed to populate grid: %v", err)
+	}
+	counts := countItems(grid)
+	if stars := counts["Star"]; stars < 80 || stars > 150 {
+		t.Errorf("Expected about 30%% of 380 cells to be stars, got %d", stars)
+	}
+	if counts["Rare"] != 3 || counts["Comet"] != 10 || counts["Base"] != 20 {
+		t.Errorf("Expected 3 rare, 10 comets and 20 bases, got %v", counts)
+	}
+}
+
+func TestPopulateOccurrence(t *testing.T) {
+	countItems := func(grid *HexGrid) (map[string]int, int) {
+		counts, empty := make(map[string]int), 0
+		for _, cells := range grid.Cells {
+			for _, cell := range cells {
+				if cell.ItemType == nil {
+					empty++
+				} else {
+					counts[cell.ItemType.Name]++
+				}
+			}
+		}
+		return counts, empty
+	}
+
+	// A system is present on 4+ on 1d6, then picked by weight
+	config := &spec.Spec{
+		Default:    "#000000",
+		Generator:  spec.GeneratorOccurrence,
+		Occurrence: spec.OccurrenceSettings{Dice: "1d6", Target: 4},
+		Items: []spec.ItemType{
+			{Name: "Red Dwarf", Weight: 3, Style: "dot", Color: "#FF4112", Dice: "2d6"},
+			{Name: "Yellow Star", Weight: 1, Style: "dot", Color: "#F7BE00"},
+		},
+	}
+	grid := New(20, 20, config)
+	if err := grid.Populate(rand.New(rand.NewSource(5))); err != nil {
+		t.Fatalf("Failed to populate grid: %v", err)
+	}
+	counts, empty := countItems(grid)
+	if empty < 160 || empty > 240 {
+		t.Errorf("Expected about half of 400 hexes empty, got %d", empty)
+	}
+	if ratio := float64(counts["Red Dwarf"]) / float64(counts["Yellow Star"]); ratio < 2 || ratio > 4.5 {
+		t.Errorf("Expected about 3 red dwarfs per yellow star, got %v", counts)
+	}
+	for _, cells := range grid.Cells {
+		for _, cell := range cells {
+			if cell.ItemType != nil && cell.ItemType.Name == "Red Dwarf" && cell.DiceResult == nil {
+				t.Fatalf("Expected every red dwarf to roll its dice, %d,%d has none", cell.Row, cell.Col)
+			}
+		}
+	}
+
+	// Every hex is present and a 2d6 table picks the item; totals of 9 and
+	// up are on no item, so those hexes stay empty
+	config = &spec.Spec{
+		Default:    "#000000",
+		Generator:  spec.GeneratorOccurrence,
+		Occurrence: spec.OccurrenceSettings{Dice: "1d6", Target: 1, Table: "2d6"},
+		Items: []spec.ItemType{
+			{Name: "Starport X", Roll: spec.RollRange{2, 2}, Style: "dot", Color: "#808080", Letter: "X"},
+			{Name: "Starport E", Roll: spec.RollRange{3, 6}, Style: "dot", Color: "#A0A0A0", Letter: "E"},
+			{Name: "Starport C", Roll: spec.RollRange{7, 8}, Style: "dot", Color: "#FFFFFF", Letter: "C"},
+		},
+	}
+	grid = New(30, 30, config)
+	if err := grid.Populate(rand.New(rand.NewSource(6))); err != nil {
+		t.Fatalf("Failed to populate grid: %v", err)
+	}
+	counts, empty = countItems(grid)
+	// 2d6 rolls 2 once in 36, 3-6 14 times, 7-8 11 times and 9-12 10 times
+	for name, want := range map[string]float64{"Starport X": 1, "Starport E": 14, "Starport C": 11, "": 10} {
+		got := float64(counts[name])
+		if name == "" {
+			got = float64(empty)
+		}
+		if expected := want / 36 * 900; math.Abs(got-expected) > 4*math.Sqrt(expected)+1 {
+			t.Errorf("Expected about %.0f hexes of %q, got %.0f", expected, name, got)
+		}
+	}
+}
+
+func TestPopulateRules(t *testing.T) {
+	config := &spec.Spec{
+		Default: "#FFFFFF",
+		Items: []spec.ItemType{
+			{Name: "Water", Percentage: 15, Style: "fill", Color: "#4169E1"},
+			{Name: "Village", Percentage: 8, Style: "dot", Color: "#FFD700",
+				Rules: []spec.Rule{{Type: spec.RuleAdjacent, Items: []string{"Water"}}}},
+			{Name: "Black Hole", Percentage: 3, Style: "dot", Color: "#000000",
+				Rules: []spec.Rule{{Type: spec.RuleExclude, Items: []string{"Black Hole"}, Distance: 2}}},
+		},
+	}
+
+	for seed := int64(1); seed <= 5; seed++ {
+		grid := New(20, 10, config)
+		if err := grid.Populate(rand.New(rand.NewSource(seed))); err != nil {
+			t.Fatalf("Seed %d: failed to populate grid: %v", seed, err)
+		}
+
+		counts := make(map[string]int)
+		for _, cells := range grid.Cells {
+			for _, cell := range cells {
+				if cell.ItemType == nil {
+					continue
+				}
+				counts[cell.ItemType.Name]++
+				if rule, broken := grid.brokenRule(cell); broken {
+					t.Errorf("Seed %d: %s at %d,%d %s", seed, cell.ItemType.Name, cell.Row, cell.Col, rule)
+				}
+			}
+		}
+		if counts["Water"] != 30 || counts["Village"] != 16 || counts["Black Hole"] != 6 {
+			t.Errorf("Seed %d: rules changed the item counts: %v", seed, counts)
+		}
+	}
+}
+
+func TestPopulateNoiseRules(t *testing.T) {
+	// Villages come from a high band far from the water, so nearly all of
+	// them break their rule and are swapped next to the water
+	config := &spec.Spec{
+		Default:   "#FFFFFF",
+		Generator: spec.GeneratorNoise,
+		Items: []spec.ItemType{
+			{Name: "Water", Style: "fill", Color: "#4169E1", Elevation: spec.Band{0, 0.3}},
+			{Name: "Village", Style: "dot", Color: "#FFD700", Elevation: spec.Band{0.8, 0.82},
+				Rules: []spec.Rule{{Type: spec.RuleAdjacent, Items: []string{"Water"}}}},
+			{Name: "Land", Style: "fill", Color: "#228B22", Elevation: spec.Band{0.3, 1}, Moisture: spec.Band{0, 1}},
+		},
+	}
+
+	for seed := int64(1); seed <= 3; seed++ {
+		grid := New(20, 10, config)
+		if err := grid.Populate(rand.New(rand.NewSource(seed))); err != nil {
+			t.Fatalf("Seed %d: failed to populate grid: %v", seed, err)
+		}
+		for _, cells := range grid.Cells {
+			for _, cell := range cells {
+				item := cell.ItemType
+				if !item.Elevation.Contains(cell.Elevation) || !item.Moisture.Contains(cell.Moisture) {
+					t.Errorf("Seed %d: %s at %d,%d has elevation %.2f and moisture %.2f outside its bands",
+						seed, item.Name, cell.Row, cell.Col, cell.Elevation, cell.Moisture)
+				}
+				if rule, broken := grid.brokenRule(cell); broken {
+					t.Errorf("Seed %d: %s at %d,%d %s", seed, item.Name, cell.Row, cell.Col, rule)
+				}
+			}
+		}
+	}
+}
+
+func TestPopulateUnsatisfiableRules(t *testing.T) {
+	// Every cell is a Village that must be next to Water, but there is none
+	config := &spec.Spec{
+		Default: "#FFFFFF",
+		Items: []spec.ItemType{
+			{Name: "Village", Percentage: 100, Style: "dot", Color: "#FFD700",
+				Rules: []spec.Rule{{Type: spec.RuleAdjacent, Items: []string{"Water"}}}},
+			{Name: "Water", Percentage: 0, Style: "fill", Color: "#4169E1"},
+		},
+	}
+
+	grid := New(4, 4, config)
+	err := grid.Populate(rand.New(rand.NewSource(1)))
+	var ruleErr *RuleError
+	if !errors.As(err, &ruleErr) {
+		t.Fatalf("Expected a RuleError, got %v", err)
+	}
+	if ruleErr.Violations != 16 {
+		t.Errorf("Expected 16 violating cells, got %d", ruleErr.Violations)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	config := &spec.Spec{
+		Default: "#F5F5DC",
+		Items: []spec.ItemType{
+			{Name: "Forest", Percentage: 40, Style: "fill", Color: "#228B22"},
+			{Name: "Star", Percentage: 20, Style: "dot", Color: "#FFD700", Dice: "4d6kh3", Letter: "G", Size: "large"},
+		},
+		Layout: spec.HexLayout{Orientation: spec.OrientationPointy, Offset: spec.OffsetEvenR},
+	}
+
+	original := New(7, 5, config)
+	original.Seed = 1234
+	if err := original.Populate(rand.New(rand.NewSource(original.Seed))); err != nil {
+		t.Fatalf("Failed to populate grid: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := original.Save(&buf); err != nil {
+		t.Fatalf("Failed to save grid: %v", err)
+	}
+	if first := original.Cells[0][0]; first.ItemType != nil && !strings.Contains(buf.String(), "label: \""+original.Label(first)+"\"") {
+		t.Errorf("Expected the saved grid to label cell 0,0 as %s", original.Label(first))
+	}
+	loaded, err := Load(&buf)
+	if err != nil {
+		t.Fatalf("Failed to load grid: %v", err)
+	}
+
+	if loaded.Rows != original.Rows || loaded.Cols != original.Cols || loaded.Seed != original.Seed {
+		t.Fatalf("Expected %dx%d grid with seed %d, got %dx%d with seed %d",
+			original.Rows, original.Cols, original.Seed, loaded.Rows, loaded.Cols, loaded.Seed)
+	}
+	if loaded.Orientation != spec.OrientationPointy || loaded.Offset != spec.OffsetEvenR {
+		t.Errorf("Expected pointy even-r layout, got %s %s", loaded.Orientation, loaded.Offset)
+	}
+	if len(loaded.ItemTypes) != 2 || !reflect.DeepEqual(loaded.ItemTypes[1], original.ItemTypes[1]) {
+		t.Errorf("Item types were not restored: %+v", loaded.ItemTypes)
+	}
+	for row := 0; row < original.Rows; row++ {
+		for col := 0; col < original.Cols; col++ {
+			a, b := original.Cells[row][col], loaded.Cells[row][col]
+			if (a.ItemType == nil) != (b.ItemType == nil) || (a.ItemType != nil && a.ItemType.Name != b.ItemType.Name) {
+				t.Fatalf("Cell %d,%d item was not restored", row, col)
+			}
+			if a.DiceResult != nil && (b.DiceResult == nil || a.DiceResult.Total != b.DiceResult.Total ||
+				a.DiceResult.Breakdown != b.DiceResult.Breakdown) {
+				t.Fatalf("Cell %d,%d dice result was not restored", row, col)
+			}
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		msg  string
+	}{
+		{"version", "version: 2\nrows: 1\ncols: 1\n", "unsupported grid file version"},
+		{"bad spec", "version: 1\nrows: 1\ncols: 1\nspec:\n  default: \"#FFF\"\n", "no items defined"},
+		{"unknown item", "version: 1\nrows: 1\ncols: 1\nspec:\n  default: \"#FFF\"\n  items:\n    - {name: A, style: fill, color: \"#000\"}\ncells:\n  - {row: 0, col: 0, item: B}\n", "unknown item: B"},
+		{"outside", "version: 1\nrows: 1\ncols: 1\nspec:\n  default: \"#FFF\"\n  items:\n    - {name: A, style: fill, color: \"#000\"}\ncells:\n  - {row: 3, col: 0, item: A}\n", "outside the 1x1 grid"},
+	}
+
+	for _, tt := range tests {
+		_, err := Load(strings.NewReader(tt.data))
+		if err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.msg, err)
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	tests := []struct {
+		layout   spec.HexLayout
+		format   string
+		row, col int
+		label    string
+	}{
+		// In the stagger layout each grid column holds two visual columns
+		{spec.HexLayout{}, spec.LabelXXYY, 0, 0, "0101"},
+		{spec.HexLayout{}, spec.LabelXXYY, 1, 0, "0201"},
+		{spec.HexLayout{}, spec.LabelXXYY, 4, 2, "0503"},
+		{spec.HexLayout{}, "", 3, 1, "0402"},
+		{spec.HexLayout{Offset: spec.OffsetOddQ}, spec.LabelXXYY, 4, 2, "0305"},
+		{spec.HexLayout{Orientation: spec.OrientationPointy}, spec.LabelA1, 4, 2, "C5"},
+		{spec.HexLayout{Offset: spec.OffsetOddQ}, spec.LabelAxial, 4, 3, "3,3"},
+	}
+	for _, tt := range tests {
+		g := New(6, 4, &spec.Spec{Default: "#FFFFFF", Layout: tt.layout, Labels: spec.LabelSettings{Format: tt.format}})
+		if label := g.Label(g.Cells[tt.row][tt.col]); label != tt.label {
+			t.Errorf("%v %q: expected cell %d,%d to be %s, got %s", tt.layout, tt.format, tt.row, tt.col, tt.label, label)
+		}
+	}
+
+	// Large grids use wider numbers
+	g := New(120, 3, &spec.Spec{Default: "#FFFFFF", Layout: spec.HexLayout{Offset: spec.OffsetEvenQ}})
+	if label := g.Label(g.Cells[99][2]); label != "003100" {
+		t.Errorf("Expected 003100, got %s", label)
+	}
+
+	for col, letters := range map[int]string{0: "A", 25: "Z", 26: "AA", 701: "ZZ", 702: "AAA"} {
+		if got := ColumnLetters(col); got != letters {
+			t.Errorf("Expected column %d to be %s, got %s", col, letters, got)
+		}
+	}
+}
+
+func BenchmarkPopulateRules(b *testing.B) {
+	config := &spec.Spec{
+		Default: "#FFFFFF",
+		Items: []spec.ItemType{
+			{Name: "Water", Percentage: 15, Style: "fill", Color: "#4169E1"},
+			{Name: "Village", Percentage: 8, Style: "dot", Color: "#FFD700",
+				Rules: []spec.Rule{{Type: spec.RuleAdjacent, Items: []string{"Water"}}}},
+			{Name: "Black Hole", Percentage: 1, Style: "dot", Color: "#000000",
+				Rules: []spec.Rule{{Type: spec.RuleExclude, Items: []string{"Black Hole"}, Distance: 2}}},
+			{Name: "Plains", Percentage: 76, Style: "fill", Color: "#90EE90"},
+		},
+	}
+	for _, size := range []int{50, 100, 200} {
+		b.Run(fmt.Sprintf("%dx%d", size, size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				grid := New(size, size, config)
+				if err := grid.Populate(rand.New(rand.NewSource(int64(i + 1)))); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*size*size), "ns/cell")
+		})
+	}
+}
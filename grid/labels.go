@@ -0,0 +1,75 @@
+package grid
+
+import (
+	"fmt"
+	"strconv"
+
+	"hexgrid/spec"
+)
+
+// ColumnRow returns the column and row a cell is drawn in, counting from 0
+// at the top left. They are the cell's Col and Row, except in the stagger
+// layout where each grid column holds two visual columns and each visual
+// row holds two grid rows.
+func (grid *HexGrid) ColumnRow(cell *HexCell) (col, row int) {
+	if grid.Offset == spec.OffsetStagger {
+		return grid.Axial(cell).Q, cell.Row / 2
+	}
+	return cell.Col, cell.Row
+}
+
+// visualSize returns the number of columns and rows the grid is drawn in
+func (grid *HexGrid) visualSize() (cols, rows int) {
+	if grid.Offset == spec.OffsetStagger {
+		cols = 2 * grid.Cols
+		if grid.Rows == 1 {
+			cols--
+		}
+		return cols, (grid.Rows + 1) / 2
+	}
+	return grid.Cols, grid.Rows
+}
+
+// LabelFormat returns the format of the grid's hex labels, which is
+// spec.LabelXXYY when labels are not drawn
+func (grid *HexGrid) LabelFormat() string {
+	if grid.Labels.Format == "" {
+		return spec.LabelXXYY
+	}
+	return grid.Labels.Format
+}
+
+// Label returns the identifier of a cell in the grid's label format, such
+// as "0304" (column 3, row 4), "C4" or "2,-1"
+func (grid *HexGrid) Label(cell *HexCell) string {
+	col, row := grid.ColumnRow(cell)
+	switch grid.LabelFormat() {
+	case spec.LabelA1:
+		return ColumnLetters(col) + strconv.Itoa(row+1)
+	case spec.LabelAxial:
+		a := grid.Axial(cell)
+		return fmt.Sprintf("%d,%d", a.Q, a.R)
+	}
+
+	// Pad both numbers to the same width, and to more than two digits on
+	// grids that need it
+	cols, rows := grid.visualSize()
+	width := len(strconv.Itoa(cols))
+	if n := len(strconv.Itoa(rows)); n > width {
+		width = n
+	}
+	if width < 2 {
+		width = 2
+	}
+	return fmt.Sprintf("%0*d%0*d", width, col+1, width, row+1)
+}
+
+// ColumnLetters names a column with letters, counting from 0: "A" to "Z",
+// then "AA", "AB" and so on
+func ColumnLetters(col int) string {
+	letters := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		letters = string(rune('A'+(col-1)%26)) + letters
+	}
+	return letters
+}
@@ -0,0 +1,146 @@
+package grid
+
+import (
+	"math"
+
+	"hexgrid/spec"
+)
+
+// Point is a position on a drawing of the grid
+type Point struct {
+	X, Y float64
+}
+
+// Layout places the hexagons of a grid on a drawing. Every renderer draws
+// from a Layout, so all outputs of a grid have the same geometry and only
+// differ in scale. Positions are measured from the top left corner of the map.
+type Layout struct {
+	Size          float64 // Distance from a hexagon's center to its corners
+	Width, Height float64 // Size of the whole map
+
+	corners [6]Point // Corners of a hexagon of size 1
+}
+
+// Layout returns the layout of the grid for hexagons of the given size
+func (grid *HexGrid) Layout(size float64) Layout {
+	return Layout{
+		Size:    size,
+		Width:   grid.width * size,
+		Height:  grid.height * size,
+		corners: hexCorners(grid.Orientation),
+	}
+}
+
+// Center returns the center of the cell's hexagon
+func (l Layout) Center(cell *HexCell) Point {
+	return Point{X: cell.X * l.Size, Y: cell.Y * l.Size}
+}
+
+// Corners returns the corners of the cell's hexagon, clockwise on screen
+// starting from the right (flat-top) or lower right (pointy-top)
+func (l Layout) Corners(cell *HexCell) [6]Point {
+	center := l.Center(cell)
+	var corners [6]Point
+	for i, corner := range l.corners {
+		corners[i] = Point{X: center.X + corner.X*l.Size, Y: center.Y + corner.Y*l.Size}
+	}
+	return corners
+}
+
+// CellAtPoint returns the cell whose hexagon contains p in layout, or nil if
+// p is outside the map. Hexagons tile the plane, so the hexagon containing a
+// point is the one with the nearest center.
+func (grid *HexGrid) CellAtPoint(l Layout, p Point) *HexCell {
+	var nearest *HexCell
+	best := math.Inf(1)
+	for _, row := range grid.Cells {
+		for _, cell := range row {
+			center := l.Center(cell)
+			if d := math.Hypot(p.X-center.X, p.Y-center.Y); d < best {
+				nearest, best = cell, d
+			}
+		}
+	}
+
+	// Beyond the edge of the map the nearest hexagon may not reach p
+	if nearest == nil || !l.contains(nearest, p) {
+		return nil
+	}
+	return nearest
+}
+
+// contains reports whether p is inside the cell's hexagon
+func (l Layout) contains(cell *HexCell, p Point) bool {
+	corners := l.Corners(cell)
+	for i, a := range corners {
+		b := corners[(i+1)%len(corners)]
+		// Corners go clockwise on screen, so the inside is on the right of
+		// each edge, where the cross product is not negative
+		if (b.X-a.X)*(p.Y-a.Y)-(b.Y-a.Y)*(p.X-a.X) < -1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+// hexCorners returns the corners of a hexagon of size 1 around the origin
+func hexCorners(orientation string) [6]Point {
+	start := 0.0
+	if orientation == spec.OrientationPointy {
+		start = math.Pi / 6
+	}
+
+	var corners [6]Point
+	for i := range corners {
+		angle := start + float64(i)*math.Pi/3
+		corners[i] = Point{X: math.Cos(angle), Y: math.Sin(angle)}
+	}
+	return corners
+}
+
+// unitCenter returns the position of an axial coordinate in a tiling of
+// hexagons of size 1, with the hex at 0,0 centered on the origin
+func (grid *HexGrid) unitCenter(a Axial) Point {
+	q, r := float64(a.Q), float64(a.R)
+	if grid.Orientation == spec.OrientationPointy {
+		// Columns are a hex width apart and each row moves 1.5 hexes down
+		return Point{X: math.Sqrt(3) * (q + r/2), Y: 1.5 * r}
+	}
+	// Visual columns are 1.5 hexes apart and each step of R moves a full hex height down
+	return Point{X: 1.5 * q, Y: math.Sqrt(3) * (r + q/2)}
+}
+
+// placeCells sets the center of every cell for hexagons of size 1, so the
+// hexagons touch without overlapping, and records the size of the map
+func (grid *HexGrid) placeCells() {
+	grid.width, grid.height = 0, 0
+	if grid.Rows == 0 || grid.Cols == 0 {
+		return
+	}
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	corners := hexCorners(grid.Orientation)
+	for _, row := range grid.Cells {
+		for _, cell := range row {
+			center := grid.unitCenter(grid.Axial(cell))
+			cell.X, cell.Y = center.X, center.Y
+
+			for _, corner := range corners {
+				minX = math.Min(minX, cell.X+corner.X)
+				minY = math.Min(minY, cell.Y+corner.Y)
+				maxX = math.Max(maxX, cell.X+corner.X)
+				maxY = math.Max(maxY, cell.Y+corner.Y)
+			}
+		}
+	}
+
+	// Move the map's top left corner to the origin
+	for _, row := range grid.Cells {
+		for _, cell := range row {
+			cell.X -= minX
+			cell.Y -= minY
+		}
+	}
+	grid.width, grid.height = maxX-minX, maxY-minY
+}
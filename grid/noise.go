@@ -0,0 +1,151 @@
+package grid
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Defaults for spec.NoiseSettings fields left at zero
+const (
+	defaultNoiseScale   = 0.15
+	defaultNoiseOctaves = 4
+)
+
+// populateNoise derives an elevation and a moisture value for every cell
+// from two coherent noise fields and gives each cell the first item type
+// whose elevation and moisture bands contain both values
+func (grid *HexGrid) populateNoise(rng *rand.Rand) {
+	scale := grid.Noise.Scale
+	if scale == 0 {
+		scale = defaultNoiseScale
+	}
+	octaves := grid.Noise.Octaves
+	if octaves == 0 {
+		octaves = defaultNoiseOctaves
+	}
+
+	elevation := newPerlin(rng)
+	moisture := newPerlin(rng)
+
+	var cells []*HexCell
+	for _, row := range grid.Cells {
+		for _, cell := range row {
+			// Sample the noise at the cell's position in a regular hex
+			// tiling so neighboring cells get similar values
+			center := grid.unitCenter(grid.Axial(cell))
+			x, y := center.X*scale, center.Y*scale
+
+			cell.Elevation = elevation.fractal(x, y, octaves)
+			cell.Moisture = moisture.fractal(x, y, octaves)
+			cells = append(cells, cell)
+		}
+	}
+
+	// Spread the values evenly over 0-1 by rank, so a band like [0, 0.3]
+	// covers the lowest 30% of the grid whatever its size
+	normalize(cells, func(cell *HexCell) *float64 { return &cell.Elevation })
+	normalize(cells, func(cell *HexCell) *float64 { return &cell.Moisture })
+
+	for _, cell := range cells {
+		for _, itemType := range grid.ItemTypes {
+			if itemType.Elevation == nil && itemType.Moisture == nil {
+				continue // only banded items take part in noise generation
+			}
+			if itemType.Elevation.Contains(cell.Elevation) && itemType.Moisture.Contains(cell.Moisture) {
+				grid.place(rng, cell, itemType)
+				break
+			}
+		}
+	}
+}
+
+// normalize replaces the value of each cell with its rank scaled to 0-1
+func normalize(cells []*HexCell, value func(*HexCell) *float64) {
+	sorted := make([]*HexCell, len(cells))
+	copy(sorted, cells)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return *value(sorted[i]) < *value(sorted[j])
+	})
+
+	for i, cell := range sorted {
+		if len(sorted) > 1 {
+			*value(cell) = float64(i) / float64(len(sorted)-1)
+		} else {
+			*value(cell) = 0
+		}
+	}
+}
+
+// perlin is a 2D gradient noise field with its own permutation table
+type perlin struct {
+	perm [512]int
+}
+
+// newPerlin creates a noise field whose permutation is drawn from rng
+func newPerlin(rng *rand.Rand) *perlin {
+	p := &perlin{}
+	for i, v := range rng.Perm(256) {
+		p.perm[i] = v
+		p.perm[i+256] = v
+	}
+	return p
+}
+
+// fractal sums octaves of noise at doubling frequencies and halving
+// amplitudes, giving large features with smaller details on top
+func (p *perlin) fractal(x, y float64, octaves int) float64 {
+	total, amplitude, frequency := 0.0, 1.0, 1.0
+	for i := 0; i < octaves; i++ {
+		total += p.noise(x*frequency, y*frequency) * amplitude
+		amplitude /= 2
+		frequency *= 2
+	}
+	return total
+}
+
+// noise returns the gradient noise value at (x, y), roughly in -1 to 1
+func (p *perlin) noise(x, y float64) float64 {
+	x0, y0 := math.Floor(x), math.Floor(y)
+	xi, yi := int(x0)&255, int(y0)&255
+	xf, yf := x-x0, y-y0
+	u, v := fade(xf), fade(yf)
+
+	aa := p.perm[p.perm[xi]+yi]
+	ab := p.perm[p.perm[xi]+yi+1]
+	ba := p.perm[p.perm[xi+1]+yi]
+	bb := p.perm[p.perm[xi+1]+yi+1]
+
+	return lerp(
+		lerp(gradient(aa, xf, yf), gradient(ba, xf-1, yf), u),
+		lerp(gradient(ab, xf, yf-1), gradient(bb, xf-1, yf-1), u),
+		v,
+	)
+}
+
+// fade is the quintic smoothing curve 6t^5 - 15t^4 + 10t^3
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+// gradient returns the dot product of (x, y) with one of eight gradient directions
+func gradient(hash int, x, y float64) float64 {
+	switch hash & 7 {
+	case 0:
+		return x + y
+	case 1:
+		return -x + y
+	case 2:
+		return x - y
+	case 3:
+		return -x - y
+	case 4:
+		return x
+	case 5:
+		return -x
+	case 6:
+		return y
+	default:
+		return -y
+	}
+}
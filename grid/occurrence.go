@@ -0,0 +1,82 @@
+package grid
+
+import (
+	"fmt"
+	"math/rand"
+
+	"hexgrid/dice"
+	"hexgrid/spec"
+)
+
+// populateOccurrence rolls the occurrence dice for every cell in turn. Each
+// cell whose total reaches the target gets an item: the one whose Roll
+// contains a roll of the table dice, or without a table one picked at
+// random in proportion to the items' weights.
+func (grid *HexGrid) populateOccurrence(rng *rand.Rand) error {
+	settings := grid.Occurrence
+	presence, err := dice.Parse(settings.Dice)
+	if err != nil {
+		return fmt.Errorf("invalid occurrence dice: %w", err)
+	}
+	var table *dice.Expr
+	if settings.Table != "" {
+		if table, err = dice.Parse(settings.Table); err != nil {
+			return fmt.Errorf("invalid occurrence table: %w", err)
+		}
+	}
+
+	for _, row := range grid.Cells {
+		for _, cell := range row {
+			if presence.Roll(rng).Total < settings.Target {
+				continue
+			}
+			var itemType *spec.ItemType
+			if table != nil {
+				itemType = grid.tableItem(table.Roll(rng).Total)
+			} else {
+				itemType = grid.weightedItem(rng)
+			}
+			// A table roll no item covers leaves the cell empty
+			if itemType != nil {
+				grid.place(rng, cell, itemType)
+			}
+		}
+	}
+	return nil
+}
+
+// tableItem returns the first item type whose Roll contains total, or nil
+func (grid *HexGrid) tableItem(total int) *spec.ItemType {
+	for _, itemType := range grid.ItemTypes {
+		if itemType.Roll.Contains(total) {
+			return itemType
+		}
+	}
+	return nil
+}
+
+// weightedItem picks an item type at random in proportion to the weights,
+// or returns nil if no item has a weight
+func (grid *HexGrid) weightedItem(rng *rand.Rand) *spec.ItemType {
+	total := 0.0
+	for _, itemType := range grid.ItemTypes {
+		total += max(itemType.Weight, 0)
+	}
+	if total == 0 {
+		return nil
+	}
+
+	pick := rng.Float64() * total
+	var last *spec.ItemType
+	for _, itemType := range grid.ItemTypes {
+		if itemType.Weight <= 0 {
+			continue
+		}
+		if pick < itemType.Weight {
+			return itemType
+		}
+		pick -= itemType.Weight
+		last = itemType
+	}
+	return last // rounding left pick just short of the total
+}
@@ -0,0 +1,94 @@
+package grid
+
+import (
+	"math/rand"
+
+	"hexgrid/spec"
+)
+
+// placeClustered assigns count empty cells to itemType as contiguous regions.
+// Each region starts at the next empty cell in seeds and grows along hex
+// neighbors until it reaches the item's ClusterSize or runs out of room,
+// then a new region is started. seeds is advanced past the cells it uses.
+func (grid *HexGrid) placeClustered(rng *rand.Rand, itemType *spec.ItemType, count int, seeds *[]*HexCell) {
+	placed := 0
+	for placed < count {
+		seed := nextEmptyCell(seeds)
+		if seed == nil {
+			return // the grid is full
+		}
+		grid.place(rng, seed, itemType)
+		placed++
+
+		// Grow the region from its frontier of empty neighboring cells
+		var frontier []*HexCell
+		inFrontier := make(map[*HexCell]bool)
+		addFrontier := func(cell *HexCell) {
+			for _, neighbor := range grid.Neighbors(cell) {
+				if neighbor.ItemType == nil && !inFrontier[neighbor] {
+					inFrontier[neighbor] = true
+					frontier = append(frontier, neighbor)
+				}
+			}
+		}
+		addFrontier(seed)
+
+		for size := 1; placed < count && len(frontier) > 0; size++ {
+			if itemType.ClusterSize > 0 && size >= itemType.ClusterSize {
+				break
+			}
+
+			i := grid.pickFrontier(rng, frontier, itemType)
+			cell := frontier[i]
+			frontier[i] = frontier[len(frontier)-1]
+			frontier = frontier[:len(frontier)-1]
+
+			grid.place(rng, cell, itemType)
+			placed++
+			addFrontier(cell)
+		}
+	}
+}
+
+// pickFrontier chooses the index of the next frontier cell to add to a region.
+// With probability Cohesion it takes the cell touching the most cells of the
+// item already, which keeps regions compact; otherwise it picks at random.
+func (grid *HexGrid) pickFrontier(rng *rand.Rand, frontier []*HexCell, itemType *spec.ItemType) int {
+	if itemType.Cohesion <= 0 || rng.Float64() >= itemType.Cohesion {
+		return rng.Intn(len(frontier))
+	}
+
+	best, bestCount, ties := 0, -1, 0
+	for i, cell := range frontier {
+		count := 0
+		for _, neighbor := range grid.Neighbors(cell) {
+			if neighbor.ItemType == itemType {
+				count++
+			}
+		}
+		switch {
+		case count > bestCount:
+			best, bestCount, ties = i, count, 1
+		case count == bestCount:
+			// Break ties uniformly at random
+			ties++
+			if rng.Intn(ties) == 0 {
+				best = i
+			}
+		}
+	}
+	return best
+}
+
+// nextEmptyCell returns the first empty cell in cells, dropping the
+// filled cells before it, or nil if every cell is filled
+func nextEmptyCell(cells *[]*HexCell) *HexCell {
+	for len(*cells) > 0 {
+		cell := (*cells)[0]
+		*cells = (*cells)[1:]
+		if cell.ItemType == nil {
+			return cell
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package grid
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"hexgrid/spec"
+)
+
+// itemCounts decides how many cells each item type without a Chance gets.
+// Dice counts are rolled in spec order, so a seed always rolls the same counts.
+func (grid *HexGrid) itemCounts(rng *rand.Rand) map[*spec.ItemType]int {
+	counts := make(map[*spec.ItemType]int)
+	var byPercentage []*spec.ItemType
+	for _, itemType := range grid.ItemTypes {
+		switch itemType.Quantity() {
+		case spec.QuantityCount:
+			counts[itemType] = grid.countExprs[itemType].Roll(rng).Total
+		case spec.QuantityRange:
+			counts[itemType] = itemType.Min
+			if itemType.Max > itemType.Min {
+				counts[itemType] += rng.Intn(itemType.Max - itemType.Min + 1)
+			}
+		case spec.QuantityPercentage:
+			byPercentage = append(byPercentage, itemType)
+		}
+	}
+	for itemType, count := range largestRemainder(grid.Rows*grid.Cols, byPercentage) {
+		counts[itemType] = count
+	}
+
+	for itemType, count := range counts {
+		counts[itemType] = clampCount(itemType, count)
+	}
+	return counts
+}
+
+// largestRemainder shares cells out between the items by their percentages.
+// Each item gets the whole part of its share, and the cells left over from
+// the rounded total of the shares go one each to the items with the largest
+// fractions, so the counts add up even when every share is small.
+func largestRemainder(cells int, items []*spec.ItemType) map[*spec.ItemType]int {
+	counts := make(map[*spec.ItemType]int, len(items))
+	fractions := make([]float64, len(items))
+	total, assigned := 0.0, 0
+	for i, itemType := range items {
+		share := float64(cells) * itemType.Percentage / 100
+		whole := math.Floor(share)
+		counts[itemType] = int(whole)
+		fractions[i] = share - whole
+		total += share
+		assigned += int(whole)
+	}
+
+	// Ties go to the item earlier in the spec
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return fractions[order[a]] > fractions[order[b]]
+	})
+	leftover := int(math.Round(total)) - assigned
+	for _, i := range order[:max(0, min(leftover, len(order)))] {
+		counts[items[i]]++
+	}
+	return counts
+}
+
+// placeByChance rolls for each of cells in turn, giving every item its
+// Chance in percent of taking the cell; the cell stays empty if none does.
+// Each item's Min and Max are then met by filling more of the cells that
+// are still empty or by emptying some of the item's cells.
+func (grid *HexGrid) placeByChance(rng *rand.Rand, items []*spec.ItemType, cells []*HexCell) {
+	placed := make(map[*spec.ItemType][]*HexCell)
+	for _, cell := range cells {
+		roll := rng.Float64() * 100
+		for _, itemType := range items {
+			if roll < itemType.Chance {
+				grid.place(rng, cell, itemType)
+				placed[itemType] = append(placed[itemType], cell)
+				break
+			}
+			roll -= itemType.Chance
+		}
+	}
+
+	empty := cells
+	for _, itemType := range items {
+		count := len(placed[itemType])
+		target := clampCount(itemType, count)
+
+		// The cells are in random order, so the last ones are random ones
+		for _, cell := range placed[itemType][min(target, count):] {
+			cell.ItemType, cell.DiceResult = nil, nil
+		}
+		for ; count < target; count++ {
+			cell := nextEmptyCell(&empty)
+			if cell == nil {
+				break // the grid is full
+			}
+			grid.place(rng, cell, itemType)
+		}
+	}
+}
+
+// clampCount keeps a number of cells within the item's Min and Max
+func clampCount(itemType *spec.ItemType, count int) int {
+	if itemType.Max > 0 && count > itemType.Max {
+		count = itemType.Max
+	}
+	return max(count, itemType.Min, 0)
+}
@@ -0,0 +1,222 @@
+package grid
+
+import (
+	"fmt"
+	"math/rand"
+
+	"hexgrid/spec"
+)
+
+// maxRuleRounds limits how many passes enforceRules makes over the grid
+const maxRuleRounds = 50
+
+// Candidates repair tries first: the cells within repairReach hexes beyond
+// the rule's range of the violation, then repairSample random cells whose
+// items have no rules
+const (
+	repairReach  = 2
+	repairSample = 32
+)
+
+// RuleError reports a placement rule that could not be satisfied
+type RuleError struct {
+	Cell       *HexCell
+	Rule       spec.Rule
+	Violations int // Number of cells still breaking a rule
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("unable to satisfy placement rules: %s at row %d, col %d %s (%d cells break rules)",
+		e.Cell.ItemType.Name, e.Cell.Row, e.Cell.Col, e.Rule, e.Violations)
+}
+
+// enforceRules repairs cells whose items break their placement rules by
+// swapping them with other cells, which keeps the number of cells of every
+// item type unchanged. A swap is only kept if it reduces the number of rule
+// violations around both cells, so the search always makes progress; it
+// gives up with a *RuleError when a full pass over the grid fixes nothing.
+//
+// Most repairs find a swap among a fixed number of nearby and sampled cells,
+// so the work grows with the number of violations rather than with the
+// violations times the cells. Only a violation none of those cells fixes
+// costs a scan of the whole grid.
+func (grid *HexGrid) enforceRules(rng *rand.Rand) error {
+	radius := 0
+	for _, itemType := range grid.ItemTypes {
+		for _, rule := range itemType.Rules {
+			if r := rule.Range(); r > radius {
+				radius = r
+			}
+		}
+	}
+	if radius == 0 {
+		return nil // no rules
+	}
+
+	var allCells []*HexCell
+	for _, row := range grid.Cells {
+		allCells = append(allCells, row...)
+	}
+
+	for round := 0; ; round++ {
+		var violating []*HexCell
+		for _, cell := range allCells {
+			if _, ok := grid.brokenRule(cell); ok {
+				violating = append(violating, cell)
+			}
+		}
+		if len(violating) == 0 {
+			return nil
+		}
+
+		progress := false
+		if round < maxRuleRounds {
+			rng.Shuffle(len(violating), func(i, j int) {
+				violating[i], violating[j] = violating[j], violating[i]
+			})
+			for _, cell := range violating {
+				if grid.repair(rng, cell, allCells, radius) {
+					progress = true
+				}
+			}
+		}
+
+		if !progress {
+			rule, _ := grid.brokenRule(violating[0])
+			return &RuleError{Cell: violating[0], Rule: rule, Violations: len(violating)}
+		}
+	}
+}
+
+// repair tries to move the item out of a cell that breaks a rule by
+// swapping it with another cell, returning whether a swap was made. It tries
+// the cells near the violation first, then a sample of cells whose items
+// have no rules, and only then the rest of the grid.
+func (grid *HexGrid) repair(rng *rand.Rand, cell *HexCell, allCells []*HexCell, radius int) bool {
+	if _, ok := grid.brokenRule(cell); !ok {
+		return false // fixed by an earlier swap
+	}
+
+	// trySwap keeps a swap with other if it reduces the violations around both cells
+	trySwap := func(other *HexCell) bool {
+		if other == cell || other.ItemType == cell.ItemType {
+			return false
+		}
+		area := append(grid.Spiral(cell, radius), grid.Spiral(other, radius)...)
+		before := grid.countBroken(area)
+		swapContents(cell, other)
+		if grid.countBroken(area) < before {
+			return true
+		}
+		swapContents(cell, other)
+		return false
+	}
+
+	nearby := grid.Spiral(cell, radius+repairReach)
+	rng.Shuffle(len(nearby), func(i, j int) {
+		nearby[i], nearby[j] = nearby[j], nearby[i]
+	})
+	for _, other := range nearby {
+		if trySwap(other) {
+			return true
+		}
+	}
+
+	for i := 0; i < repairSample; i++ {
+		other := allCells[rng.Intn(len(allCells))]
+		if other.ItemType != nil && len(other.ItemType.Rules) > 0 {
+			continue
+		}
+		if trySwap(other) {
+			return true
+		}
+	}
+
+	for _, i := range rng.Perm(len(allCells)) {
+		if trySwap(allCells[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// countBroken returns the number of cells breaking a rule, counting
+// duplicated cells only once
+func (grid *HexGrid) countBroken(cells []*HexCell) int {
+	seen := make(map[*HexCell]bool, len(cells))
+	count := 0
+	for _, cell := range cells {
+		if seen[cell] {
+			continue
+		}
+		seen[cell] = true
+		if _, ok := grid.brokenRule(cell); ok {
+			count++
+		}
+	}
+	return count
+}
+
+// brokenRule returns the first rule of the cell's item that the cell breaks
+func (grid *HexGrid) brokenRule(cell *HexCell) (spec.Rule, bool) {
+	if cell.ItemType == nil {
+		return spec.Rule{}, false
+	}
+
+	for _, rule := range cell.ItemType.Rules {
+		if !grid.satisfies(cell, rule) {
+			return rule, true
+		}
+	}
+	return spec.Rule{}, false
+}
+
+// satisfies reports whether a cell meets a placement rule
+func (grid *HexGrid) satisfies(cell *HexCell, rule spec.Rule) bool {
+	switch rule.Type {
+	case spec.RuleAdjacent:
+		for _, neighbor := range grid.Neighbors(cell) {
+			if isOneOf(neighbor, rule.Items) {
+				return true
+			}
+		}
+		return false
+	case spec.RuleExclude:
+		for _, other := range grid.Spiral(cell, rule.Range())[1:] {
+			if isOneOf(other, rule.Items) {
+				return false
+			}
+		}
+		return true
+	case spec.RuleSurrounded:
+		for _, neighbor := range grid.Neighbors(cell) {
+			if !isOneOf(neighbor, rule.Items) {
+				return false
+			}
+		}
+		return true
+	}
+	return true
+}
+
+// isOneOf reports whether the cell holds an item with one of the given names
+func isOneOf(cell *HexCell, names []string) bool {
+	if cell.ItemType == nil {
+		return false
+	}
+	for _, name := range names {
+		if cell.ItemType.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// swapContents exchanges the items and dice results of two cells, along
+// with the elevation and moisture that chose the items on noise grids
+func swapContents(a, b *HexCell) {
+	a.ItemType, b.ItemType = b.ItemType, a.ItemType
+	a.DiceResult, b.DiceResult = b.DiceResult, a.DiceResult
+	a.Elevation, b.Elevation = b.Elevation, a.Elevation
+	a.Moisture, b.Moisture = b.Moisture, a.Moisture
+}
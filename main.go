@@ -6,37 +6,147 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
-	"time"
+	"strconv"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/widget"
+
+	"hexgrid/cli"
+	"hexgrid/generate"
+	"hexgrid/grid"
+	"hexgrid/spec"
 )
 
-type Config struct {
-	YAMLPath     string
-	OutputPath   string
-	GridRows     int
-	GridCols     int
-	OutputFormat string // "svg" or "pdf"
-}
+// Choices of the PDF scale in the GUI
+const (
+	fitFromSpec = "From spec"
+	fitToPage   = "Fit to page"
+	fitHexSize  = "Hex size"
+)
 
 func main() {
+	// A command or flag on the command line selects the headless CLI;
+	// otherwise the GUI is started.
+	if cli.IsCommand(os.Args[1:]) {
+		os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
+	}
+
 	myApp := app.New()
 	myWindow := myApp.NewWindow("Hex Grid Generator")
-	myWindow.Resize(fyne.NewSize(600, 400))
+	myWindow.Resize(fyne.NewSize(1100, 700))
 
-	config := &Config{
+	config := &generate.Config{
 		GridRows:     25,
 		GridCols:     10,
-		OutputFormat: "svg", // Default to SVG
+		OutputFormat: "html", // SVG with the HTML preview page
 	}
 
 	// Output path label (declared early so it can be used in YAML selection)
 	outputPathLabel := widget.NewLabel("No output file selected")
 
+	// Live preview of the map, regenerated when a spec is chosen or saved, a
+	// size or seed is entered, or on Update Preview and Reroll
+	preview := newMapPreview()
+	previewStatus := widget.NewLabel("Select a YAML configuration to preview the map")
+
+	// Editing: with "Edit Hexes" on, tapping a hex opens its editor
+	var undoBtn, redoBtn *widget.Button
+	var openCell *cellEditor // The open hex editor, if any
+	updateUndoRedo := func() {
+		if history := preview.History(); history != nil && history.CanUndo() {
+			undoBtn.Enable()
+		} else {
+			undoBtn.Disable()
+		}
+		if history := preview.History(); history != nil && history.CanRedo() {
+			redoBtn.Enable()
+		} else {
+			redoBtn.Disable()
+		}
+	}
+	undo := func() {
+		if history := preview.History(); history != nil && history.Undo() != nil {
+			preview.Redraw()
+			if openCell != nil {
+				openCell.Refresh()
+			}
+		}
+		updateUndoRedo()
+	}
+	redo := func() {
+		if history := preview.History(); history != nil && history.Redo() != nil {
+			preview.Redraw()
+			if openCell != nil {
+				openCell.Refresh()
+			}
+		}
+		updateUndoRedo()
+	}
+	undoBtn = widget.NewButton("Undo", undo)
+	redoBtn = widget.NewButton("Redo", redo)
+	updateUndoRedo()
+	editCheck := widget.NewCheck("Edit Hexes", func(checked bool) {
+		preview.OnTapped = nil
+		if checked {
+			preview.OnTapped = func(cell *grid.HexCell) {
+				if openCell != nil {
+					openCell.Hide()
+				}
+				editor := showCellEditor(myWindow, preview, cell, updateUndoRedo)
+				editor.dialog.SetOnClosed(func() {
+					if openCell == editor {
+						openCell = nil
+					}
+				})
+				openCell = editor
+			}
+		}
+	})
+	myWindow.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: fyne.KeyModifierShortcutDefault}, func(fyne.Shortcut) { undo() })
+	myWindow.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: fyne.KeyModifierShortcutDefault | fyne.KeyModifierShift}, func(fyne.Shortcut) { redo() })
+
+	var seedErr error // Why the seed field isn't a seed, if it isn't
+	updatePreview := func() {
+		if config.YAMLPath == "" || config.GridRows <= 0 || config.GridCols <= 0 {
+			return
+		}
+		if seedErr != nil {
+			previewStatus.SetText(seedErr.Error())
+			return
+		}
+		hexGrid, err := generate.NewGrid(config)
+		if err != nil {
+			previewStatus.SetText(err.Error())
+			return
+		}
+		if openCell != nil {
+			// Its hex belongs to the grid being replaced
+			openCell.Hide()
+		}
+		preview.SetGrid(hexGrid)
+		updateUndoRedo()
+		previewStatus.SetText(fmt.Sprintf("Preview: %dx%d, seed %d", hexGrid.Rows, hexGrid.Cols, hexGrid.Seed))
+	}
+
+	// discardEdits runs replace, which replaces the preview, once the user
+	// has agreed to lose any hexes they have edited in it
+	discardEdits := func(replace func()) {
+		if history := preview.History(); history == nil || !history.CanUndo() {
+			replace()
+			return
+		}
+		dialog.ShowConfirm("Discard Edits?", "The preview has edited hexes, which will be lost. Continue?", func(ok bool) {
+			if ok {
+				replace()
+			}
+		}, myWindow)
+	}
+
 	// YAML file selection dropdown
 	yamlSelectLabel := widget.NewLabel("Select YAML Configuration:")
 	yamlSelectDropdown := widget.NewSelect([]string{}, func(selected string) {
@@ -53,6 +163,7 @@ func main() {
 
 			// Auto-generate output path when YAML file is selected
 			generateOutputPath(config, outputPathLabel)
+			discardEdits(updatePreview)
 		}
 	})
 
@@ -110,7 +221,27 @@ func main() {
 	// Populate the dropdown on startup
 	populateYAMLDropdown()
 
-	// Grid size inputs
+	// Spec editor for the selected YAML file, in a window of its own
+	editSpecBtn := widget.NewButton("Edit Spec", func() {
+		if config.YAMLPath == "" {
+			dialog.ShowError(fmt.Errorf("please select a YAML file"), myWindow)
+			return
+		}
+		editorWindow := myApp.NewWindow("Edit " + filepath.Base(config.YAMLPath))
+		editor, err := newSpecEditor(editorWindow, config.YAMLPath)
+		if err != nil {
+			dialog.ShowError(err, myWindow)
+			return
+		}
+		// Show the saved spec in the preview
+		editor.OnSaved = func() { discardEdits(updatePreview) }
+		editorWindow.SetContent(editor.Content())
+		editorWindow.Resize(fyne.NewSize(750, 500))
+		editorWindow.Show()
+	})
+
+	// Grid size inputs; pressing Enter in one updates the preview
+	submitPreview := func(string) { discardEdits(updatePreview) }
 	rowsInput := widget.NewEntry()
 	rowsInput.SetText(fmt.Sprintf("%d", config.GridRows))
 	rowsInput.OnChanged = func(value string) {
@@ -118,6 +249,7 @@ func main() {
 			config.GridRows = val
 		}
 	}
+	rowsInput.OnSubmitted = submitPreview
 
 	colsInput := widget.NewEntry()
 	colsInput.SetText(fmt.Sprintf("%d", config.GridCols))
@@ -126,18 +258,86 @@ func main() {
 			config.GridCols = val
 		}
 	}
+	colsInput.OnSubmitted = submitPreview
+
+	// Seed input (blank for a random seed)
+	seedInput := widget.NewEntry()
+	seedInput.SetPlaceHolder("random")
+	seedInput.Validator = func(value string) error {
+		_, err := parseSeed(value)
+		return err
+	}
+	seedInput.OnChanged = func(value string) {
+		var seed int64
+		if seed, seedErr = parseSeed(value); seedErr == nil {
+			config.Seed = seed
+		}
+	}
+	seedInput.OnSubmitted = submitPreview
+	lastSeedLabel := widget.NewLabel("")
 
 	// Output format selection
 	outputFormatLabel := widget.NewLabel("Output Format:")
-	svgRadio := widget.NewRadioGroup([]string{"SVG", "PDF"}, func(selected string) {
+	svgRadio := widget.NewRadioGroup([]string{"SVG", "PDF", "PNG"}, func(selected string) {
 		if selected == "SVG" {
-			config.OutputFormat = "svg"
+			// SVG mode also writes the HTML page that is opened in the browser
+			config.OutputFormat = "html"
 		} else if selected == "PDF" {
 			config.OutputFormat = "pdf"
+		} else if selected == "PNG" {
+			config.OutputFormat = "png"
 		}
 	})
 	svgRadio.SetSelected("SVG") // Default to SVG
 
+	// PNG resolution; blank gives the size of the SVG
+	dpiInput := widget.NewEntry()
+	dpiInput.SetPlaceHolder("96")
+	dpiInput.OnChanged = func(value string) {
+		config.Raster.DPI = 0
+		if val, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+			config.Raster.DPI = val
+		}
+	}
+
+	// PDF page setup; anything left blank uses the spec's page settings
+	pageSizeSelect := widget.NewSelect(spec.PageSizes, func(selected string) {
+		config.Page.Size = selected
+	})
+	pageSizeSelect.PlaceHolder = "From spec"
+	pageWidthInput := widget.NewEntry()
+	pageWidthInput.SetPlaceHolder("custom width")
+	pageWidthInput.OnChanged = func(value string) {
+		config.Page.Width = strings.TrimSpace(value)
+	}
+	pageHeightInput := widget.NewEntry()
+	pageHeightInput.SetPlaceHolder("custom height")
+	pageHeightInput.OnChanged = func(value string) {
+		config.Page.Height = strings.TrimSpace(value)
+	}
+	orientationSelect := widget.NewSelect([]string{spec.OrientationLandscape, spec.OrientationPortrait}, func(selected string) {
+		config.Page.Orientation = selected
+	})
+	orientationSelect.PlaceHolder = "From spec"
+	marginInput := widget.NewEntry()
+	marginInput.SetPlaceHolder("20mm")
+	marginInput.OnChanged = func(value string) {
+		config.Page.Margin = strings.TrimSpace(value)
+	}
+	hexSizeInput := widget.NewEntry()
+	hexSizeInput.SetPlaceHolder("e.g. 1in")
+	hexSizeInput.OnChanged = func(value string) {
+		config.Page.HexSize = strings.TrimSpace(value)
+	}
+	fitSelect := widget.NewSelect([]string{fitFromSpec, fitToPage, fitHexSize}, func(selected string) {
+		config.Page.Fit = nil
+		if selected != fitFromSpec {
+			fit := selected == fitToPage
+			config.Page.Fit = &fit
+		}
+	})
+	fitSelect.SetSelected(fitFromSpec)
+
 	// Output file selection
 	outputSelectBtn := widget.NewButton("Select Output File", func() {
 		// Get the executable path to find the app bundle location
@@ -179,6 +379,24 @@ func main() {
 		}, myWindow)
 	})
 
+	// showSaved reports the files written for a grid
+	showSaved := func(hexGrid *grid.HexGrid) {
+		lastSeedLabel.SetText(fmt.Sprintf("Last seed: %d", hexGrid.Seed))
+		if config.OutputFormat == "pdf" {
+			// For PDF, show success message
+			dialog.ShowInformation("Success", "PDF hex grid generated successfully!", myWindow)
+		} else if config.OutputFormat == "png" {
+			// For PNG, open the image in the default viewer
+			openInBrowser(config.OutputPath + ".png")
+		} else {
+			// For SVG, open the generated HTML file in the browser
+			htmlPath := config.OutputPath + ".html"
+			openInBrowser(htmlPath)
+		}
+		// regenerate the output path
+		generateOutputPath(config, outputPathLabel)
+	}
+
 	// Generate button
 	generateBtn := widget.NewButton("Generate Hex Grid", func() {
 		if config.YAMLPath == "" {
@@ -189,22 +407,56 @@ func main() {
 			dialog.ShowError(fmt.Errorf("please select an output file"), myWindow)
 			return
 		}
+		if seedErr != nil {
+			dialog.ShowError(seedErr, myWindow)
+			return
+		}
 
-		err := generateHexGrid(config)
-		if err != nil {
-			dialog.ShowError(err, myWindow)
-		} else {
-			if config.OutputFormat == "pdf" {
-				// For PDF, show success message
-				dialog.ShowInformation("Success", "PDF hex grid generated successfully!", myWindow)
+		// The new map replaces the preview; Save Preview keeps the edits instead
+		discardEdits(func() {
+			hexGrid, err := generate.Grid(config)
+			if err != nil {
+				dialog.ShowError(err, myWindow)
 			} else {
-				// For SVG, open the generated HTML file in the browser
-				htmlPath := config.OutputPath + ".html"
-				openInBrowser(htmlPath)
+				if openCell != nil {
+					openCell.Hide()
+				}
+				preview.SetGrid(hexGrid)
+				updateUndoRedo()
+				showSaved(hexGrid)
 			}
-			// regenerate the output path
-			generateOutputPath(config, outputPathLabel)
+		})
+	})
+
+	// Preview controls; rerolling picks a new seed and updates the preview
+	updatePreviewBtn := widget.NewButton("Update Preview", func() { discardEdits(updatePreview) })
+	rerollBtn := widget.NewButton("Reroll", func() {
+		discardEdits(func() {
+			seedInput.SetText(strconv.FormatInt(grid.NewSeed(), 10))
+			updatePreview()
+		})
+	})
+	zoomInBtn := widget.NewButton("+", func() { preview.Zoom(previewZoomStep) })
+	zoomOutBtn := widget.NewButton("-", func() { preview.Zoom(1 / previewZoomStep) })
+	fitBtn := widget.NewButton("Fit", preview.Fit)
+
+	savePreviewBtn := widget.NewButton("Save Preview", func() {
+		if preview.Grid() == nil {
+			dialog.ShowError(fmt.Errorf("nothing to save; select a YAML file to preview a map"), myWindow)
+			return
+		}
+		if config.OutputPath == "" {
+			dialog.ShowError(fmt.Errorf("please select an output file"), myWindow)
+			return
+		}
+
+		// Save the map as shown, with any edits, in the selected output format
+		err := generate.Save(config, preview.Grid())
+		if err != nil {
+			dialog.ShowError(err, myWindow)
+			return
 		}
+		showSaved(preview.Grid())
 	})
 
 	// Layout
@@ -212,7 +464,7 @@ func main() {
 		widget.NewLabel("Hex Grid Generator"),
 		widget.NewSeparator(),
 		yamlSelectLabel,
-		container.NewHBox(yamlSelectDropdown, refreshBtn),
+		container.NewHBox(yamlSelectDropdown, refreshBtn, editSpecBtn),
 		widget.NewSeparator(),
 		container.NewHBox(
 			container.NewVBox(
@@ -223,10 +475,27 @@ func main() {
 				widget.NewLabel("Grid Columns:"),
 				colsInput,
 			),
+			container.NewVBox(
+				widget.NewLabel("Seed:"),
+				seedInput,
+			),
 		),
+		lastSeedLabel,
 		widget.NewSeparator(),
 		outputFormatLabel,
-		svgRadio,
+		container.NewHBox(svgRadio, widget.NewLabel("PNG DPI:"), dpiInput),
+		widget.NewLabel("PDF Page:"),
+		container.NewHBox(
+			container.NewVBox(widget.NewLabel("Size:"), pageSizeSelect),
+			container.NewVBox(widget.NewLabel("Width:"), pageWidthInput),
+			container.NewVBox(widget.NewLabel("Height:"), pageHeightInput),
+			container.NewVBox(widget.NewLabel("Orientation:"), orientationSelect),
+		),
+		container.NewHBox(
+			container.NewVBox(widget.NewLabel("Margin:"), marginInput),
+			container.NewVBox(widget.NewLabel("Hex Size:"), hexSizeInput),
+			container.NewVBox(widget.NewLabel("Scale:"), fitSelect),
+		),
 		widget.NewSeparator(),
 		container.NewHBox(widget.NewLabel("Output File:"), outputSelectBtn),
 		outputPathLabel,
@@ -234,59 +503,48 @@ func main() {
 		generateBtn,
 	)
 
-	myWindow.SetContent(form)
+	previewPanel := container.NewBorder(
+		container.NewVBox(
+			container.NewHBox(updatePreviewBtn, rerollBtn, zoomInBtn, zoomOutBtn, fitBtn, savePreviewBtn),
+			container.NewHBox(editCheck, undoBtn, redoBtn),
+		),
+		previewStatus, nil, nil,
+		preview,
+	)
+	split := container.NewHSplit(container.NewVScroll(form), previewPanel)
+	split.Offset = 0.45
+
+	myWindow.SetContent(split)
 	myWindow.ShowAndRun()
 }
 
+// parseSeed parses the seed field, which is blank for a random seed
+func parseSeed(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	seed, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("seed must be a whole number, or blank for a random one: %q", s)
+	}
+	return seed, nil
+}
+
 func parseInt(s string) (int, error) {
-	var i int
-	_, err := fmt.Sscanf(s, "%d", &i)
-	return i, err
+	return strconv.Atoi(strings.TrimSpace(s))
 }
 
 // generateOutputPath automatically generates an output path based on the YAML file name and timestamp
-func generateOutputPath(config *Config, outputPathLabel *widget.Label) {
+func generateOutputPath(config *generate.Config, outputPathLabel *widget.Label) {
 	if config.YAMLPath == "" {
 		return
 	}
 
-	// Get the base name of the YAML file (without extension)
-	yamlBaseName := filepath.Base(config.YAMLPath)
-	yamlNameWithoutExt := yamlBaseName[:len(yamlBaseName)-len(filepath.Ext(yamlBaseName))]
-
-	// Generate timestamp
-	timestamp := time.Now().Format("2006-01-02-15-04-05")
-
-	// Get the executable path to find the app bundle location
-	execPath, err := os.Executable()
-	if err != nil {
-		execPath = "."
-	}
-
-	// Check if we're running from an app bundle
-	appBundlePath := filepath.Join(filepath.Dir(execPath), "..", "..", "..")
-	generatedGridsDir := filepath.Join(appBundlePath, "Contents", "Resources", "generated-grids")
-
-	// If not in app bundle, try current directory
-	if _, err := os.Stat(generatedGridsDir); os.IsNotExist(err) {
-		currentDir, err := os.Getwd()
-		if err != nil {
-			currentDir = "."
-		}
-		generatedGridsDir = filepath.Join(currentDir, "generated-grids")
-	}
-
-	// Create generated-grids directory if it doesn't exist
-	if _, err := os.Stat(generatedGridsDir); os.IsNotExist(err) {
-		os.MkdirAll(generatedGridsDir, 0755)
-	}
-
-	// Generate output path
-	outputFileName := fmt.Sprintf("%s-%s", yamlNameWithoutExt, timestamp)
-	config.OutputPath = filepath.Join(generatedGridsDir, outputFileName)
+	config.OutputPath = generate.DefaultOutputPath(config.YAMLPath)
 
 	// Update the label
-	outputPathLabel.SetText(outputFileName)
+	outputPathLabel.SetText(filepath.Base(config.OutputPath))
 }
 
 // openInBrowser opens the specified file in the default browser
@@ -311,42 +569,3 @@ func openInBrowser(filePath string) {
 		}
 	}()
 }
-
-func generateHexGrid(config *Config) error {
-	// Load YAML configuration
-	yamlConfig, err := LoadYAMLConfig(config.YAMLPath)
-	if err != nil {
-		return fmt.Errorf("failed to load YAML config: %w", err)
-	}
-
-	// Create hex grid
-	grid := CreateHexGrid(config.GridRows, config.GridCols, yamlConfig)
-
-	// Populate grid with items
-	grid.PopulateGrid()
-
-	if config.OutputFormat == "pdf" {
-		// Generate PDF file
-		pdfPath := config.OutputPath + ".pdf"
-		err = GeneratePDF(grid, pdfPath)
-		if err != nil {
-			return fmt.Errorf("failed to generate PDF: %w", err)
-		}
-	} else {
-		// Generate SVG file
-		svgPath := config.OutputPath + ".svg"
-		err = GenerateSVG(grid, svgPath)
-		if err != nil {
-			return fmt.Errorf("failed to generate SVG: %w", err)
-		}
-
-		// Generate HTML file
-		htmlPath := config.OutputPath + ".html"
-		err = GenerateHTML(grid, svgPath, htmlPath)
-		if err != nil {
-			return fmt.Errorf("failed to generate HTML: %w", err)
-		}
-	}
-
-	return nil
-}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestYAMLFileDiscovery(t *testing.T) {
+	// Test that we can read YAML files from the grid-specs directory
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+
+	gridSpecsDir := filepath.Join(currentDir, "grid-specs")
+
+	// Read all YAML files from the grid-specs directory
+	files, err := os.ReadDir(gridSpecsDir)
+	if err != nil {
+		t.Fatalf("Failed to read grid-specs directory: %v", err)
+	}
+
+	var yamlFiles []string
+	for _, file := range files {
+		if !file.IsDir() && filepath.Ext(file.Name()) == ".yaml" {
+			yamlFiles = append(yamlFiles, file.Name())
+		}
+	}
+
+	// We should have at least the sample files
+	if len(yamlFiles) < 3 {
+		t.Errorf("Expected at least 3 YAML files, got %d", len(yamlFiles))
+	}
+
+	// Check for expected files
+	expectedFiles := []string{"sample_config.yaml", "fantasy-world.yaml", "desert-world.yaml", "space.yaml"}
+	for _, expected := range expectedFiles {
+		found := false
+		for _, file := range yamlFiles {
+			if file == expected {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Expected file %s not found in grid-specs directory", expected)
+		}
+	}
+}
+
+func TestParseSeed(t *testing.T) {
+	for _, tt := range []struct {
+		text string
+		seed int64
+		ok   bool
+	}{
+		{"", 0, true},
+		{"  42 ", 42, true},
+		{"-7", -7, true},
+		{"12abc", 0, false},
+		{"1.5", 0, false},
+	} {
+		seed, err := parseSeed(tt.text)
+		if seed != tt.seed || (err == nil) != tt.ok {
+			t.Errorf("parseSeed(%q) = %d, %v, expected %d", tt.text, seed, err, tt.seed)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/widget"
+
+	"hexgrid/grid"
+	"hexgrid/render"
+)
+
+// Zoom limits of the preview, relative to the size of the SVG
+const (
+	minPreviewScale = 0.02
+	maxPreviewScale = 20.0
+	previewZoomStep = 1.25 // Zoom factor of one scroll step or zoom button press
+)
+
+// mapPreview shows a hex grid inside the window, drawn by the PNG renderer.
+// Scrolling zooms around the pointer and dragging pans the map.
+type mapPreview struct {
+	widget.BaseWidget
+
+	// OnTapped is called with the cell that was tapped, if set
+	OnTapped func(cell *grid.HexCell)
+
+	grid    *grid.HexGrid
+	history *grid.History // Edits made to the grid
+	scale   float64       // Size of the map relative to its SVG
+	offset  fyne.Position // Position of the SVG's top left corner in the widget
+	fit     bool          // Fit the map to the widget once it has a size
+
+	raster *canvas.Raster
+}
+
+// newMapPreview creates an empty preview
+func newMapPreview() *mapPreview {
+	p := &mapPreview{scale: 1}
+	p.raster = canvas.NewRaster(p.draw)
+	p.ExtendBaseWidget(p)
+	return p
+}
+
+// CreateRenderer implements fyne.Widget
+func (p *mapPreview) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(p.raster)
+}
+
+// Grid returns the grid on show, or nil
+func (p *mapPreview) Grid() *grid.HexGrid {
+	return p.grid
+}
+
+// History returns the edits made to the grid on show
+func (p *mapPreview) History() *grid.History {
+	return p.history
+}
+
+// SetGrid shows a grid, zoomed to fit, with no edits to undo
+func (p *mapPreview) SetGrid(g *grid.HexGrid) {
+	p.grid = g
+	p.history = &grid.History{}
+	p.Fit()
+}
+
+// Redraw draws the grid again after its cells have changed
+func (p *mapPreview) Redraw() {
+	p.raster.Refresh()
+}
+
+// Fit zooms and centers the map so all of it shows
+func (p *mapPreview) Fit() {
+	size := p.Size()
+	if p.grid == nil || size.Width <= 0 || size.Height <= 0 {
+		// Wait for the widget to be laid out
+		p.fit = true
+		p.raster.Refresh()
+		return
+	}
+	p.fit = false
+
+	width, height := render.SVGSize(p.grid)
+	p.scale = clampScale(math.Min(float64(size.Width)/width, float64(size.Height)/height))
+	p.offset = fyne.NewPos(
+		(size.Width-float32(width*p.scale))/2,
+		(size.Height-float32(height*p.scale))/2,
+	)
+	p.raster.Refresh()
+}
+
+// Resize implements fyne.CanvasObject, fitting a map that was set before
+// the widget had a size
+func (p *mapPreview) Resize(size fyne.Size) {
+	p.BaseWidget.Resize(size)
+	if p.fit {
+		p.Fit()
+	}
+}
+
+// ZoomAt zooms by factor, keeping the point of the map at pos in place
+func (p *mapPreview) ZoomAt(factor float64, pos fyne.Position) {
+	scale := clampScale(p.scale * factor)
+	change := float32(scale / p.scale)
+	p.scale = scale
+	p.offset = fyne.NewPos(pos.X-(pos.X-p.offset.X)*change, pos.Y-(pos.Y-p.offset.Y)*change)
+	p.raster.Refresh()
+}
+
+// Zoom zooms by factor around the middle of the widget
+func (p *mapPreview) Zoom(factor float64) {
+	size := p.Size()
+	p.ZoomAt(factor, fyne.NewPos(size.Width/2, size.Height/2))
+}
+
+// Scrolled implements fyne.Scrollable
+func (p *mapPreview) Scrolled(event *fyne.ScrollEvent) {
+	switch {
+	case event.Scrolled.DY > 0:
+		p.ZoomAt(previewZoomStep, event.Position)
+	case event.Scrolled.DY < 0:
+		p.ZoomAt(1/previewZoomStep, event.Position)
+	}
+}
+
+// Dragged implements fyne.Draggable
+func (p *mapPreview) Dragged(event *fyne.DragEvent) {
+	p.offset = p.offset.Add(event.Dragged)
+	p.raster.Refresh()
+}
+
+// DragEnd implements fyne.Draggable
+func (p *mapPreview) DragEnd() {}
+
+// Tapped implements fyne.Tappable
+func (p *mapPreview) Tapped(event *fyne.PointEvent) {
+	if p.OnTapped == nil {
+		return
+	}
+	if cell := p.CellAt(event.Position); cell != nil {
+		p.OnTapped(cell)
+	}
+}
+
+// CellAt returns the cell shown at pos, or nil
+func (p *mapPreview) CellAt(pos fyne.Position) *grid.HexCell {
+	if p.grid == nil {
+		return nil
+	}
+	point := grid.Point{
+		X: float64(pos.X-p.offset.X)/p.scale - render.SVGMargin,
+		Y: float64(pos.Y-p.offset.Y)/p.scale - render.SVGMargin,
+	}
+	return p.grid.CellAtPoint(p.grid.Layout(render.HexSize), point)
+}
+
+// draw renders the visible part of the map at the raster's pixel size
+func (p *mapPreview) draw(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	if p.grid == nil || p.Size().Width <= 0 {
+		return img
+	}
+
+	// The raster has more pixels than the widget has units on high DPI screens
+	pixels := float64(w) / float64(p.Size().Width)
+	origin := grid.Point{X: float64(p.offset.X) * pixels, Y: float64(p.offset.Y) * pixels}
+	err := render.DrawRaster(img, p.grid, p.scale*pixels, origin)
+	if err != nil {
+		// Not fatal - the preview just misses some text
+		fmt.Printf("Failed to draw preview: %v\n", err)
+	}
+	return img
+}
+
+// clampScale keeps a preview scale within the zoom limits
+func clampScale(scale float64) float64 {
+	return math.Max(minPreviewScale, math.Min(maxPreviewScale, scale))
+}
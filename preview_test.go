@@ -0,0 +1,109 @@
+package main
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+
+	"hexgrid/grid"
+	"hexgrid/render"
+	"hexgrid/spec"
+)
+
+// previewGrid returns a small grid whose hexes are all filled
+func previewGrid(t *testing.T) *grid.HexGrid {
+	t.Helper()
+	config := &spec.Spec{
+		Default: "#FFFFFF",
+		Items:   []spec.ItemType{{Name: "Sea", Percentage: 100, Style: "fill", Color: "#0000FF"}},
+	}
+	g := grid.New(4, 6, config)
+	if err := g.Populate(rand.New(rand.NewSource(1))); err != nil {
+		t.Fatalf("Failed to populate grid: %v", err)
+	}
+	return g
+}
+
+func TestMapPreviewFit(t *testing.T) {
+	test.NewApp()
+	g := previewGrid(t)
+
+	// A grid set before the preview has a size is fitted once it has one
+	p := newMapPreview()
+	p.SetGrid(g)
+	p.Resize(fyne.NewSize(400, 300))
+
+	width, height := render.SVGSize(g)
+	scale := math.Min(400/width, 300/height)
+	if math.Abs(p.scale-scale) > 1e-9 {
+		t.Errorf("Expected scale %g, got %g", scale, p.scale)
+	}
+	// The map is centered
+	left, top := float64(p.offset.X), float64(p.offset.Y)
+	right, bottom := 400-(left+width*p.scale), 300-(top+height*p.scale)
+	if math.Abs(left-right) > 0.01 || math.Abs(top-bottom) > 0.01 {
+		t.Errorf("Expected the map centered, got margins %g, %g, %g, %g", left, top, right, bottom)
+	}
+
+	// Hexes are drawn in the item's color where the preview puts them
+	layout := g.Layout(render.HexSize)
+	center := layout.Center(g.Cells[1][1])
+	x, y := left+(render.SVGMargin+center.X)*p.scale, top+(render.SVGMargin+center.Y)*p.scale
+	img := p.draw(400, 300).(*image.RGBA)
+	if c := img.RGBAAt(int(x), int(y)); c.R != 0 || c.G != 0 || c.B != 0xFF {
+		t.Errorf("Expected the center of a hex to be blue, got %v", c)
+	}
+
+	// and tapping there finds the hex
+	var tapped *grid.HexCell
+	p.OnTapped = func(cell *grid.HexCell) { tapped = cell }
+	test.TapAt(p, fyne.NewPos(float32(x), float32(y)))
+	if tapped != g.Cells[1][1] {
+		t.Errorf("Expected tapping at %g,%g to find cell 1,1, got %v", x, y, tapped)
+	}
+	if cell := p.CellAt(fyne.NewPos(1, 1)); cell != nil {
+		t.Errorf("Expected no cell in the corner of the preview, got %d,%d", cell.Row, cell.Col)
+	}
+}
+
+func TestMapPreviewZoomAndPan(t *testing.T) {
+	test.NewApp()
+	p := newMapPreview()
+	p.Resize(fyne.NewSize(400, 300))
+	p.SetGrid(previewGrid(t))
+
+	// Zooming keeps the point under the pointer in place
+	pos := fyne.NewPos(120, 80)
+	mapX := (float64(pos.X) - float64(p.offset.X)) / p.scale
+	scale := p.scale
+	p.Scrolled(&fyne.ScrollEvent{PointEvent: fyne.PointEvent{Position: pos}, Scrolled: fyne.NewDelta(0, 10)})
+	if math.Abs(p.scale-scale*previewZoomStep) > 1e-9 {
+		t.Errorf("Expected scrolling up to zoom in to %g, got %g", scale*previewZoomStep, p.scale)
+	}
+	if x := (float64(pos.X) - float64(p.offset.X)) / p.scale; math.Abs(x-mapX) > 0.01 {
+		t.Errorf("Expected map position %g under the pointer, got %g", mapX, x)
+	}
+
+	// Zoom stays within its limits
+	for i := 0; i < 100; i++ {
+		p.Zoom(previewZoomStep)
+	}
+	if p.scale != maxPreviewScale {
+		t.Errorf("Expected zoom to stop at %g, got %g", maxPreviewScale, p.scale)
+	}
+
+	offset := p.offset
+	p.Dragged(&fyne.DragEvent{Dragged: fyne.NewDelta(15, -5)})
+	if p.offset != offset.Add(fyne.NewPos(15, -5)) {
+		t.Errorf("Expected dragging to move the map from %v by 15,-5, got %v", offset, p.offset)
+	}
+
+	p.Fit()
+	if p.scale >= maxPreviewScale {
+		t.Errorf("Expected Fit to zoom out, got scale %g", p.scale)
+	}
+}
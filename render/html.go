@@ -0,0 +1,440 @@
+package render
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"hexgrid/grid"
+)
+
+// viewerData is the grid data the HTML viewer's script reads. Cells carry
+// their own data as attributes of their SVG groups.
+type viewerData struct {
+	Seed  int64        `json:"seed"`
+	Items []viewerItem `json:"items"`
+}
+
+// viewerItem is an item type, as listed in viewerData
+type viewerItem struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+	Style string `json:"style"`
+}
+
+// HTML writes a self-contained interactive viewer for the hex grid to w: a
+// page with the SVG that can be panned and zoomed, tooltips and selection of
+// hexes, a legend whose items can be hidden, and a search box. It needs no
+// network access, so the file can be shared and opened offline.
+func HTML(w io.Writer, g *grid.HexGrid) error {
+	data := viewerData{Seed: g.Seed, Items: make([]viewerItem, len(g.ItemTypes))}
+	for i, itemType := range g.ItemTypes {
+		data.Items[i] = viewerItem{Name: itemType.Name, Color: itemType.Color, Style: itemType.Style}
+	}
+	// json.Marshal escapes <, > and &, so the data can't end the script element
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode viewer data: %w", err)
+	}
+
+	out := bufio.NewWriter(w)
+	out.WriteString(htmlHead)
+	fmt.Fprintf(out, `
+    <div class="container">
+        <div class="svg-container" id="map-container" style="--empty-fill: %s;">
+            `, escapeXML(g.DefaultColor))
+	writeSVG(out, g, true)
+	out.WriteString(`
+        </div>
+        `)
+	writeLegend(out, g)
+	fmt.Fprintf(out, `
+    </div>
+    <div class="tooltip" id="tooltip" hidden></div>
+    <script type="application/json" id="grid-data">%s</script>`, dataJSON)
+	out.WriteString(htmlFoot)
+
+	err = out.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to write HTML: %w", err)
+	}
+	return nil
+}
+
+// writeLegend writes the HTML legend of the grid's item types, with a
+// checkbox to show or hide each one, and the panel of selected hexes
+func writeLegend(out *bufio.Writer, g *grid.HexGrid) {
+	out.WriteString(`<div class="legend">
+    <h3>Item Legend</h3>
+    <div class="legend-items">`)
+
+	for i, itemType := range g.ItemTypes {
+		var symbol string
+		if itemType.Style == "fill" {
+			symbol = fmt.Sprintf(`<div class="legend-symbol fill" style="background-color: %s;"></div>`, escapeXML(itemType.Color))
+		} else {
+			symbol = fmt.Sprintf(`<div class="legend-symbol dot"><div class="dot" style="background-color: %s;"></div></div>`, escapeXML(itemType.Color))
+		}
+
+		fmt.Fprintf(out, `
+      <label class="legend-item">
+        <input type="checkbox" class="legend-toggle" data-item="%d" checked>
+        %s
+        <span class="legend-name">%s (%s)</span>
+      </label>`, i, symbol, escapeXML(itemType.Name), itemType.QuantityString())
+	}
+
+	fmt.Fprintf(out, `
+    </div>
+    <p class="legend-seed">Seed: %d</p>
+    <h3>Selected</h3>
+    <ul class="selection" id="selection"><li>Click a hex to select it, shift-click to add more</li></ul>
+  </div>`, g.Seed)
+}
+
+// htmlHead is the HTML page up to the map, and htmlFoot holds the viewer's script
+const htmlHead = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Hex Grid Generator</title>
+    <style>
+        body {
+            margin: 0;
+            padding: 20px;
+            font-family: Arial, sans-serif;
+            background-color: #f5f5f5;
+        }
+        .toolbar {
+            display: flex;
+            align-items: center;
+            gap: 8px;
+            margin-bottom: 12px;
+        }
+        .toolbar input {
+            width: 220px;
+            padding: 4px 8px;
+        }
+        .search-count {
+            font-size: 12px;
+            color: #888;
+        }
+        .container {
+            display: flex;
+            gap: 20px;
+            max-width: 100%;
+        }
+        .svg-container {
+            flex: 1;
+            background: white;
+            border-radius: 8px;
+            box-shadow: 0 2px 10px rgba(0,0,0,0.1);
+            overflow: hidden;
+            height: 80vh;
+            cursor: grab;
+            touch-action: none;
+        }
+        .svg-container.panning {
+            cursor: grabbing;
+        }
+        .svg-container svg {
+            display: block;
+            margin: 0;
+        }
+        .cell:hover path {
+            stroke: #000;
+        }
+        .cell.selected path {
+            stroke: #D62728;
+            stroke-width: 3;
+        }
+        .cell.hidden path {
+            fill: var(--empty-fill);
+        }
+        .cell.hidden > :not(path):not(.label) {
+            display: none;
+        }
+        #map.searching .cell:not(.match) {
+            opacity: 0.25;
+        }
+        .tooltip {
+            position: fixed;
+            pointer-events: none;
+            background: rgba(0,0,0,0.8);
+            color: white;
+            padding: 6px 8px;
+            border-radius: 4px;
+            font-size: 12px;
+            white-space: pre-line;
+        }
+        .legend {
+            width: 250px;
+            background: white;
+            padding: 20px;
+            border-radius: 8px;
+            box-shadow: 0 2px 10px rgba(0,0,0,0.1);
+            height: fit-content;
+        }
+        .legend h3 {
+            margin-top: 0;
+            color: #333;
+        }
+        .legend-items {
+            display: flex;
+            flex-direction: column;
+            gap: 10px;
+        }
+        .legend-item {
+            display: flex;
+            align-items: center;
+            gap: 10px;
+            cursor: pointer;
+        }
+        .legend-symbol {
+            width: 20px;
+            height: 20px;
+            border: 1px solid #333;
+            display: flex;
+            align-items: center;
+            justify-content: center;
+        }
+        .legend-symbol.fill {
+            border-radius: 0;
+        }
+        .legend-symbol.dot {
+            border-radius: 50%;
+            background: white;
+        }
+        .legend-symbol .dot {
+            width: 8px;
+            height: 8px;
+            border-radius: 50%;
+        }
+        .legend-name {
+            font-size: 14px;
+            color: #555;
+        }
+        .legend-seed {
+            font-size: 12px;
+            color: #888;
+        }
+        .selection {
+            margin: 0;
+            padding-left: 18px;
+            font-size: 13px;
+            color: #555;
+        }
+        h1 {
+            color: #333;
+            margin-bottom: 20px;
+        }
+    </style>
+</head>
+<body>
+    <h1>Hex Grid Generator</h1>
+    <div class="toolbar">
+        <input type="search" id="search" placeholder="Search hex or item, Enter to go">
+        <span class="search-count" id="search-count"></span>
+        <button type="button" id="zoom-in" title="Zoom in">+</button>
+        <button type="button" id="zoom-out" title="Zoom out">&minus;</button>
+        <button type="button" id="zoom-reset">Reset view</button>
+    </div>`
+
+const htmlFoot = `
+    <script>
+    (function () {
+        var data = JSON.parse(document.getElementById('grid-data').textContent);
+        var svg = document.getElementById('map');
+        var container = document.getElementById('map-container');
+        var tooltip = document.getElementById('tooltip');
+        var cells = svg.querySelectorAll('.cell');
+
+        // Pan and zoom move the viewBox, within limits around the whole map
+        var box = svg.viewBox.baseVal;
+        var home = {x: box.x, y: box.y, width: box.width, height: box.height};
+        var view = home;
+
+        function setView(v) {
+            view = v;
+            svg.setAttribute('viewBox', v.x + ' ' + v.y + ' ' + v.width + ' ' + v.height);
+        }
+
+        // toMap converts a position on screen to map coordinates
+        function toMap(clientX, clientY) {
+            var point = svg.createSVGPoint();
+            point.x = clientX;
+            point.y = clientY;
+            return point.matrixTransform(svg.getScreenCTM().inverse());
+        }
+
+        function zoom(factor, clientX, clientY) {
+            var width = Math.min(Math.max(view.width / factor, home.width / 50), home.width * 4);
+            var scale = view.width / width;
+            var p = toMap(clientX, clientY);
+            setView({
+                x: p.x - (p.x - view.x) / scale,
+                y: p.y - (p.y - view.y) / scale,
+                width: width,
+                height: view.height / scale
+            });
+        }
+
+        function zoomCenter(factor) {
+            var rect = svg.getBoundingClientRect();
+            zoom(factor, rect.left + rect.width / 2, rect.top + rect.height / 2);
+        }
+
+        function centerOn(cell) {
+            var rect = cell.getBoundingClientRect();
+            var p = toMap(rect.left + rect.width / 2, rect.top + rect.height / 2);
+            setView({x: p.x - view.width / 2, y: p.y - view.height / 2, width: view.width, height: view.height});
+        }
+
+        svg.addEventListener('wheel', function (event) {
+            event.preventDefault();
+            zoom(event.deltaY < 0 ? 1.2 : 1 / 1.2, event.clientX, event.clientY);
+        }, {passive: false});
+        document.getElementById('zoom-in').addEventListener('click', function () { zoomCenter(1.5); });
+        document.getElementById('zoom-out').addEventListener('click', function () { zoomCenter(1 / 1.5); });
+        document.getElementById('zoom-reset').addEventListener('click', function () { setView(home); });
+
+        // Dragging pans the map; a press that hardly moves is a click
+        var drag = null;
+        svg.addEventListener('pointerdown', function (event) {
+            drag = {x: event.clientX, y: event.clientY, view: view, moved: false};
+            svg.setPointerCapture(event.pointerId);
+        });
+        svg.addEventListener('pointermove', function (event) {
+            if (drag) {
+                var dx = event.clientX - drag.x, dy = event.clientY - drag.y;
+                if (Math.abs(dx) + Math.abs(dy) > 4) {
+                    drag.moved = true;
+                    container.classList.add('panning');
+                }
+                if (drag.moved) {
+                    var scale = svg.getScreenCTM().a;
+                    setView({x: drag.view.x - dx / scale, y: drag.view.y - dy / scale, width: drag.view.width, height: drag.view.height});
+                    tooltip.hidden = true;
+                    return;
+                }
+            }
+            showTooltip(event);
+        });
+        svg.addEventListener('pointerup', function (event) {
+            if (drag && !drag.moved) {
+                select(cellAt(event), event.shiftKey);
+            }
+            drag = null;
+            container.classList.remove('panning');
+        });
+        svg.addEventListener('pointerleave', function () { tooltip.hidden = true; });
+
+        // cellAt returns the hex under the pointer; pointer capture retargets
+        // events to the SVG, so the position is looked up
+        function cellAt(event) {
+            var target = document.elementFromPoint(event.clientX, event.clientY);
+            return target && target.closest ? target.closest('.cell') : null;
+        }
+
+        // describe returns the lines shown for a hex: its coordinate, item and dice roll
+        function describe(cell) {
+            var item = cell.getAttribute('data-item');
+            var lines = [cell.getAttribute('data-label'), item === null ? 'Empty' : data.items[item].name];
+            if (cell.hasAttribute('data-dice')) {
+                lines.push('Dice: ' + cell.getAttribute('data-dice'));
+            }
+            return lines;
+        }
+
+        function showTooltip(event) {
+            var cell = cellAt(event);
+            if (!cell) {
+                tooltip.hidden = true;
+                return;
+            }
+            tooltip.textContent = describe(cell).join('\n');
+            tooltip.style.left = (event.clientX + 12) + 'px';
+            tooltip.style.top = (event.clientY + 12) + 'px';
+            tooltip.hidden = false;
+        }
+
+        // Clicking selects a hex; shift-click adds it to the selection or removes it
+        var selected = [];
+        var selection = document.getElementById('selection');
+        var selectionHint = selection.firstElementChild;
+
+        function select(cell, add) {
+            if (!add) {
+                // Clicking the only selected hex again clears the selection
+                if (selected.length === 1 && selected[0] === cell) {
+                    cell = null;
+                }
+                selected.forEach(function (c) { c.classList.remove('selected'); });
+                selected = [];
+            }
+            if (cell) {
+                var i = selected.indexOf(cell);
+                if (i >= 0) {
+                    selected.splice(i, 1);
+                    cell.classList.remove('selected');
+                } else {
+                    selected.push(cell);
+                    cell.classList.add('selected');
+                }
+            }
+            selection.textContent = '';
+            if (selected.length === 0) {
+                selection.appendChild(selectionHint);
+            }
+            selected.forEach(function (c) {
+                var li = document.createElement('li');
+                li.textContent = describe(c).join(' – ');
+                selection.appendChild(li);
+            });
+        }
+
+        // The legend's checkboxes hide and show the hexes of each item type
+        document.querySelectorAll('.legend-toggle').forEach(function (toggle) {
+            toggle.addEventListener('change', function () {
+                var item = toggle.getAttribute('data-item');
+                svg.querySelectorAll('.cell[data-item="' + item + '"]').forEach(function (cell) {
+                    cell.classList.toggle('hidden', !toggle.checked);
+                });
+            });
+        });
+
+        // Search matches a hex's coordinate exactly or any part of an item name
+        var search = document.getElementById('search');
+        var searchCount = document.getElementById('search-count');
+        var matches = [];
+        search.addEventListener('input', function () {
+            var query = search.value.trim().toLowerCase();
+            matches.forEach(function (cell) { cell.classList.remove('match'); });
+            matches = [];
+            svg.classList.toggle('searching', query !== '');
+            if (query === '') {
+                searchCount.textContent = '';
+                return;
+            }
+            cells.forEach(function (cell) {
+                var item = cell.getAttribute('data-item');
+                if (cell.getAttribute('data-label').toLowerCase() === query ||
+                    (item !== null && data.items[item].name.toLowerCase().indexOf(query) >= 0)) {
+                    cell.classList.add('match');
+                    matches.push(cell);
+                }
+            });
+            searchCount.textContent = matches.length === 1 ? '1 match' : matches.length + ' matches';
+        });
+        search.addEventListener('keydown', function (event) {
+            if (event.key === 'Enter' && matches.length > 0) {
+                centerOn(matches[0]);
+                select(matches[0], false);
+            }
+        });
+    })();
+    </script>
+</body>
+</html>`
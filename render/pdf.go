@@ -0,0 +1,401 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/jung-kurt/gofpdf"
+
+	"hexgrid/grid"
+	"hexgrid/spec"
+)
+
+// Marks around the map area of tiled pages, in mm
+const (
+	tileOverlap = 10.0 // strip of the map repeated on neighboring pages of a tiled map
+	markLength  = 5.0  // length of crop marks
+	markGap     = 2.0  // space between crop marks and the map area
+)
+
+// The legend of a single page map is drawn in a column at the right of the
+// map area, in mm
+const (
+	legendWidth = 60.0
+	legendGap   = 5.0 // space between the map and the legend
+)
+
+// pdfPage is the page setup and hexagon size of a PDF document, in mm
+type pdfPage struct {
+	width, height float64
+	margin        float64 // blank border around the map on every page
+	overlap       float64 // strip of the map repeated on neighboring pages of a tiled map
+	size          float64 // Size of hexagon (center to corner)
+}
+
+// newPDFPage resolves the grid's page settings
+func newPDFPage(g *grid.HexGrid) (pdfPage, error) {
+	layout, err := g.Page.Layout()
+	if err != nil {
+		return pdfPage{}, fmt.Errorf("invalid page settings: %w", err)
+	}
+
+	page := pdfPage{
+		width:  layout.Width,
+		height: layout.Height,
+		margin: layout.Margin,
+		size:   layout.HexSize / math.Sqrt(3), // the hex size is measured across the flat sides
+	}
+	// Keep the overlap small on tiny pages so every page still shows new parts of the map
+	page.overlap = math.Min(tileOverlap, math.Min(page.areaWidth(), page.areaHeight())/4)
+
+	if layout.Fit {
+		// The map's size is proportional to the hexagon size
+		unit := g.Layout(1)
+		width := page.mapWidth()
+		if width <= 0 {
+			// No room beside the legend, which then gets its own page
+			width = page.areaWidth()
+		}
+		page.size = math.Min(width/unit.Width, page.areaHeight()/unit.Height)
+	}
+	return page, nil
+}
+
+// areaWidth and areaHeight are the size of the map area inside the margins
+func (page pdfPage) areaWidth() float64  { return page.width - 2*page.margin }
+func (page pdfPage) areaHeight() float64 { return page.height - 2*page.margin }
+
+// mapWidth is the width of the map area left of the legend column
+func (page pdfPage) mapWidth() float64 { return page.areaWidth() - legendWidth - legendGap }
+
+// legendX is the left edge of the legend column
+func (page pdfPage) legendX() float64 { return page.width - page.margin - legendWidth }
+
+// placeMap returns where the top left of a single page map goes: centered
+// left of the legend if it fits there, or else centered in the map area,
+// with the legend on a page of its own
+func (page pdfPage) placeMap(mapWidth, mapHeight float64) (x, y float64, legendPage bool) {
+	y = page.margin + (page.areaHeight()-mapHeight)/2
+	if mapWidth <= page.mapWidth() {
+		return page.margin + (page.mapWidth()-mapWidth)/2, y, false
+	}
+	return page.margin + (page.areaWidth()-mapWidth)/2, y, true
+}
+
+// tile is one page of a map that is too large to print on a single page
+type tile struct {
+	Col, Row int     // position of the page when the map is assembled
+	X, Y     float64 // top left of the part of the map shown on the page, in mm from the map's top left
+}
+
+// PDF writes a PDF representation of the hex grid to w, using the grid's
+// page settings. A map that does not fit on one page is printed at the same
+// scale across several pages, with overlapping edges, crop and registration
+// marks and page labels, followed by a page with the legend and the
+// assembly order.
+func PDF(w io.Writer, g *grid.HexGrid) error {
+	page, err := newPDFPage(g)
+	if err != nil {
+		return err
+	}
+
+	// Create new PDF document; the page size is already in the chosen orientation
+	pdf := gofpdf.NewCustom(&gofpdf.InitType{
+		OrientationStr: "P",
+		UnitStr:        "mm",
+		Size:           gofpdf.SizeType{Wd: page.width, Ht: page.height},
+	})
+
+	// Set font
+	pdf.SetFont("Arial", "", 10)
+
+	usableWidth, usableHeight := page.areaWidth(), page.areaHeight()
+	layout := g.Layout(page.size)
+	mapWidth, mapHeight := layout.Width, layout.Height
+	tiles, tileCols, tileRows := pdfTiles(mapWidth, mapHeight, usableWidth, usableHeight, page.overlap)
+
+	if len(tiles) == 1 {
+		pdf.AddPage()
+		startX, startY, legendPage := page.placeMap(mapWidth, mapHeight)
+		drawMap(pdf, g, layout, startX, startY, 0, 0, mapWidth, mapHeight)
+
+		// Add legend
+		if legendPage {
+			pdf.AddPage()
+		}
+		addLegend(pdf, g, page)
+		return pdf.Output(w)
+	}
+
+	for _, t := range tiles {
+		pdf.AddPage()
+
+		pdf.ClipRect(page.margin, page.margin, usableWidth, usableHeight, false)
+		drawMap(pdf, g, layout, page.margin-t.X, page.margin-t.Y, t.X, t.Y, t.X+usableWidth, t.Y+usableHeight)
+		pdf.ClipEnd()
+
+		page.drawTileMarks(pdf, t, tileCols, tileRows)
+	}
+
+	pdf.AddPage()
+	addAssemblyGuide(pdf, tileCols, tileRows, page.margin)
+	addLegend(pdf, g, page)
+
+	// Write PDF
+	return pdf.Output(w)
+}
+
+// pdfTiles splits a map into pages that each show areaWidth x areaHeight
+// of it, overlapping their neighbors by overlap. It also returns the
+// number of columns and rows of pages.
+func pdfTiles(mapWidth, mapHeight, areaWidth, areaHeight, overlap float64) (tiles []tile, cols, rows int) {
+	cols = tileCount(mapWidth, areaWidth, overlap)
+	rows = tileCount(mapHeight, areaHeight, overlap)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			tiles = append(tiles, tile{
+				Col: col,
+				Row: row,
+				X:   float64(col) * (areaWidth - overlap),
+				Y:   float64(row) * (areaHeight - overlap),
+			})
+		}
+	}
+	return tiles, cols, rows
+}
+
+// tileCount returns how many pages of size area, overlapping by overlap, cover length
+func tileCount(length, area, overlap float64) int {
+	if length <= area {
+		return 1
+	}
+	return 1 + int(math.Ceil((length-area)/(area-overlap)))
+}
+
+// tileLabel names the page at col, row with a column letter and a row
+// number, like "A1" for the top left page
+func tileLabel(col, row int) string {
+	return fmt.Sprintf("%s%d", grid.ColumnLetters(col), row+1)
+}
+
+// drawMap draws the hexagons that lie in the part of the map from minX, minY
+// to maxX, maxY, with the map's top left at originX, originY on the page
+func drawMap(pdf *gofpdf.Fpdf, g *grid.HexGrid, layout grid.Layout, originX, originY, minX, minY, maxX, maxY float64) {
+	size := layout.Size
+
+	// Draw hexagons
+	for row := 0; row < g.Rows; row++ {
+		for col := 0; col < g.Cols; col++ {
+			cell := g.Cells[row][col]
+
+			// Skip hexagons outside the part of the map being drawn
+			center := layout.Center(cell)
+			if center.X < minX-size || center.X > maxX+size || center.Y < minY-size || center.Y > maxY+size {
+				continue
+			}
+			drawHexagon(pdf, styleFor(g, layout, cell), originX, originY)
+		}
+	}
+}
+
+// drawTileMarks draws the crop marks, registration marks, overlap lines and
+// labels around the map area of a tiled page
+func (page pdfPage) drawTileMarks(pdf *gofpdf.Fpdf, t tile, cols, rows int) {
+	left, top := page.margin, page.margin
+	right, bottom := left+page.areaWidth(), top+page.areaHeight()
+	lineWidth := pdf.GetLineWidth()
+
+	// Dashed lines show where the next page's part of the map begins, so
+	// pages can be trimmed on one side and laid over the overlap
+	pdf.SetDrawColor(150, 150, 150)
+	pdf.SetLineWidth(0.2)
+	pdf.SetDashPattern([]float64{2, 2}, 0)
+	if t.Col < cols-1 {
+		pdf.Line(right-page.overlap, top, right-page.overlap, bottom)
+	}
+	if t.Row < rows-1 {
+		pdf.Line(left, bottom-page.overlap, right, bottom-page.overlap)
+	}
+	pdf.SetDashPattern([]float64{}, 0)
+
+	// Crop marks at the corners of the map area
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.SetLineWidth(0.1)
+	for _, corner := range []struct{ x, y, dx, dy float64 }{
+		{left, top, -1, -1}, {right, top, 1, -1}, {left, bottom, -1, 1}, {right, bottom, 1, 1},
+	} {
+		pdf.Line(corner.x+corner.dx*markGap, corner.y, corner.x+corner.dx*(markGap+markLength), corner.y)
+		pdf.Line(corner.x, corner.y+corner.dy*markGap, corner.x, corner.y+corner.dy*(markGap+markLength))
+	}
+
+	// Registration marks in the middle of each edge, with the label of the
+	// page that continues the map on that side
+	midX, midY := (left+right)/2, (top+bottom)/2
+	offset := page.margin / 2
+	pdf.SetFont("Arial", "B", 8)
+	pdf.SetTextColor(0, 0, 0)
+	for _, edge := range []struct {
+		x, y     float64
+		col, row int
+	}{
+		{midX, top - offset, t.Col, t.Row - 1},
+		{midX, bottom + offset, t.Col, t.Row + 1},
+		{left - offset, midY, t.Col - 1, t.Row},
+		{right + offset, midY, t.Col + 1, t.Row},
+	} {
+		drawRegistrationMark(pdf, edge.x, edge.y)
+		if edge.col >= 0 && edge.col < cols && edge.row >= 0 && edge.row < rows {
+			pdf.Text(edge.x-2, edge.y+7, tileLabel(edge.col, edge.row))
+		}
+	}
+	pdf.SetLineWidth(lineWidth)
+
+	// Page label in the top left margin
+	pdf.SetFont("Arial", "", 8)
+	pdf.Text(left, top-offset+1, fmt.Sprintf("Page %s (%d of %d)", tileLabel(t.Col, t.Row), t.Row*cols+t.Col+1, cols*rows))
+}
+
+// drawRegistrationMark draws a circle with crosshairs centered at x, y
+func drawRegistrationMark(pdf *gofpdf.Fpdf, x, y float64) {
+	const radius = 2.0
+	pdf.Circle(x, y, radius, "D")
+	pdf.Line(x-radius-1, y, x+radius+1, y)
+	pdf.Line(x, y-radius-1, x, y+radius+1)
+}
+
+// addAssemblyGuide draws the arrangement of the pages of a tiled map
+func addAssemblyGuide(pdf *gofpdf.Fpdf, cols, rows int, margin float64) {
+	pdf.SetFont("Arial", "B", 12)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Text(margin, margin, "Page Layout")
+
+	// Fit the guide into the space left of the legend
+	cellSize := math.Min(15, math.Min(120/float64(cols), 120/float64(rows)))
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.SetFont("Arial", "", math.Min(10, cellSize*1.5))
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			x := margin + float64(col)*cellSize
+			y := margin + 5 + float64(row)*cellSize
+			pdf.Rect(x, y, cellSize, cellSize, "D")
+			pdf.Text(x+1, y+cellSize/2+1, tileLabel(col, row))
+		}
+	}
+}
+
+// drawHexagon draws a hexagon and its contents, offset by originX, originY
+func drawHexagon(pdf *gofpdf.Fpdf, style hexStyle, originX, originY float64) {
+	lineWidth := pdf.GetLineWidth()
+
+	var points []gofpdf.PointType
+	for _, corner := range style.Corners {
+		points = append(points, gofpdf.PointType{X: originX + corner.X, Y: originY + corner.Y})
+	}
+
+	// Convert hex color to RGB
+	r, g, b := hexToRGB(style.Fill)
+	pdf.SetFillColor(r, g, b)
+
+	r, g, b = hexToRGB(style.Stroke)
+	pdf.SetDrawColor(r, g, b)
+	pdf.SetLineWidth(style.StrokeWidth)
+
+	// Draw hexagon
+	pdf.Polygon(points, "FD")
+
+	// Add the letter, the dot with its black outline, the dice result and the label
+	drawText(pdf, style.Letter, originX, originY)
+	if dot := style.Dot; dot != nil {
+		r, g, b = hexToRGB(dot.Color)
+		pdf.SetFillColor(r, g, b)
+		pdf.SetDrawColor(0, 0, 0)
+		pdf.SetLineWidth(dot.OutlineWidth)
+		pdf.Circle(originX+dot.X, originY+dot.Y, dot.Radius, "FD")
+	}
+	drawText(pdf, style.Dice, originX, originY)
+	drawText(pdf, style.Label, originX, originY)
+
+	pdf.SetLineWidth(lineWidth)
+}
+
+// drawText draws text on a hex, offset by originX, originY; it does nothing for nil
+func drawText(pdf *gofpdf.Fpdf, text *hexText, originX, originY float64) {
+	if text == nil {
+		return
+	}
+
+	// Font sizes are in points and the layout is in mm
+	pdf.SetFont("Arial", "", text.FontSize*72/25.4)
+	r, g, b := hexToRGB(text.Color)
+	pdf.SetTextColor(r, g, b)
+
+	x := originX + text.X
+	if text.Centered {
+		x -= pdf.GetStringWidth(text.Text) / 2
+	}
+	pdf.Text(x, originY+text.Y, text.Text)
+}
+
+// addLegend adds a legend to the PDF
+func addLegend(pdf *gofpdf.Fpdf, g *grid.HexGrid, page pdfPage) {
+	// Position legend in top-right corner
+	legendX := page.legendX()
+	legendY := page.margin
+
+	pdf.SetFont("Arial", "B", 12)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Text(legendX, legendY, "Item Legend")
+
+	pdf.SetFont("Arial", "", 10)
+	yOffset := legendY + 8
+
+	for _, itemType := range g.ItemTypes {
+		// Draw symbol
+		symbolX := legendX
+		symbolY := yOffset - 3
+
+		if itemType.Style == "fill" {
+			// Draw filled square
+			r, g, b := hexToRGB(itemType.Color)
+			pdf.SetFillColor(r, g, b)
+			pdf.Rect(symbolX, symbolY, 4, 4, "F")
+		} else {
+			// Draw circle with dot
+			pdf.SetFillColor(255, 255, 255)
+			pdf.Circle(symbolX+2, symbolY+2, 2, "F")
+			pdf.SetDrawColor(0, 0, 0)
+			pdf.Circle(symbolX+2, symbolY+2, 2, "D")
+
+			r, g, b := hexToRGB(itemType.Color)
+			pdf.SetFillColor(r, g, b)
+			pdf.Circle(symbolX+2, symbolY+2, 1, "F")
+		}
+
+		// Draw text
+		pdf.SetTextColor(0, 0, 0)
+		text := fmt.Sprintf("%s (%s)", itemType.Name, itemType.QuantityString())
+		if itemType.Dice != "" {
+			text += fmt.Sprintf(" - %s", itemType.Dice)
+		}
+		pdf.Text(symbolX+8, symbolY+3, text)
+
+		yOffset += 6
+	}
+
+	// Add the seed so the map can be regenerated
+	pdf.SetFont("Arial", "", 8)
+	pdf.SetTextColor(100, 100, 100)
+	pdf.Text(legendX, yOffset+2, fmt.Sprintf("Seed: %d", g.Seed))
+}
+
+// hexToRGB converts hex color string to RGB values
+func hexToRGB(hex string) (int, int, int) {
+	// Specs are validated when loaded, so this only defaults to black for
+	// colors set by hand
+	r, g, b, err := spec.ParseColor(hex)
+	if err != nil {
+		return 0, 0, 0
+	}
+	return r, g, b
+}
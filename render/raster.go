@@ -0,0 +1,304 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+
+	"hexgrid/grid"
+)
+
+// svgDPI is the resolution of SVG pixels, so a raster image at this DPI is
+// the size of the SVG
+const svgDPI = 96.0
+
+// jpegQuality is the quality of JPEG output, from 1 to 100
+const jpegQuality = 90
+
+// Limits on the size of raster images, which are drawn in memory
+const (
+	maxRasterSide   = 20000       // pixels along either side
+	maxRasterPixels = 100_000_000 // pixels in all, 400 MB of RGBA
+)
+
+// RasterOptions sets the size of PNG and JPEG images
+type RasterOptions struct {
+	DPI   float64 // Resolution, where 96 (the default) gives the size of the SVG in pixels
+	Width int     // Image width in pixels, which overrides DPI when set
+}
+
+// scale returns the number of image pixels per SVG pixel for an SVG of the given width
+func (opts RasterOptions) scale(svgWidth float64) (float64, error) {
+	switch {
+	case opts.Width < 0:
+		return 0, fmt.Errorf("invalid image width: %d", opts.Width)
+	case opts.Width > 0:
+		return float64(opts.Width) / svgWidth, nil
+	case opts.DPI < 0 || math.IsNaN(opts.DPI) || math.IsInf(opts.DPI, 0):
+		return 0, fmt.Errorf("invalid image DPI: %g", opts.DPI)
+	case opts.DPI > 0:
+		return opts.DPI / svgDPI, nil
+	}
+	return 1, nil
+}
+
+// PNG writes a PNG image of the hex grid to w. It is the same picture as the
+// SVG, with a transparent background.
+func PNG(w io.Writer, g *grid.HexGrid, opts RasterOptions) error {
+	img, err := Raster(g, opts, color.Transparent)
+	if err != nil {
+		return err
+	}
+	err = png.Encode(w, img)
+	if err != nil {
+		return fmt.Errorf("failed to write PNG: %w", err)
+	}
+	return nil
+}
+
+// JPEG writes a JPEG image of the hex grid to w. It is the same picture as
+// the SVG, on a white background.
+func JPEG(w io.Writer, g *grid.HexGrid, opts RasterOptions) error {
+	img, err := Raster(g, opts, color.White)
+	if err != nil {
+		return err
+	}
+	err = jpeg.Encode(w, img, &jpeg.Options{Quality: jpegQuality})
+	if err != nil {
+		return fmt.Errorf("failed to write JPEG: %w", err)
+	}
+	return nil
+}
+
+// Raster draws the hex grid as it appears in the SVG onto a new image with
+// the given background
+func Raster(g *grid.HexGrid, opts RasterOptions, background color.Color) (*image.RGBA, error) {
+	svgWidth, svgHeight := SVGSize(g)
+	scale, err := opts.scale(svgWidth)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the size before it is an int, which a huge one would overflow
+	fullWidth, fullHeight := math.Ceil(svgWidth*scale), math.Ceil(svgHeight*scale)
+	if opts.Width > 0 {
+		fullWidth = float64(opts.Width)
+	}
+	if fullWidth > maxRasterSide || fullHeight > maxRasterSide || fullWidth*fullHeight > maxRasterPixels {
+		return nil, fmt.Errorf("image of %.0fx%.0f pixels is too large (at most %d pixels a side and %d in all); lower the DPI or width", fullWidth, fullHeight, maxRasterSide, maxRasterPixels)
+	}
+	width, height := int(fullWidth), int(fullHeight)
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("image of %dx%d pixels is empty", width, height)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	err = DrawRaster(img, g, scale, grid.Point{})
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// DrawRaster draws the hex grid onto img as it appears in the SVG, scaled
+// by scale and with the SVG's top left corner at origin. Hexes outside img
+// are skipped, so showing part of a large map is quick.
+func DrawRaster(img *image.RGBA, g *grid.HexGrid, scale float64, origin grid.Point) error {
+	layout := g.Layout(HexSize * scale)
+	margin := SVGMargin * scale
+	canvas := &rasterCanvas{img: img, originX: origin.X + margin, originY: origin.Y + margin, faces: map[float64]font.Face{}}
+
+	// Text can reach a little past the hexagon
+	reach := 1.5 * layout.Size
+	bounds := img.Bounds()
+	for row := 0; row < g.Rows; row++ {
+		for col := 0; col < g.Cols; col++ {
+			cell := g.Cells[row][col]
+			center := layout.Center(cell)
+			x, y := center.X+canvas.originX, center.Y+canvas.originY
+			if x+reach < float64(bounds.Min.X) || x-reach > float64(bounds.Max.X) ||
+				y+reach < float64(bounds.Min.Y) || y-reach > float64(bounds.Max.Y) {
+				continue
+			}
+			style := styleFor(g, layout, cell)
+
+			canvas.fillPolygon(style.Corners[:], style.Fill)
+			canvas.strokePolygon(style.Corners[:], style.StrokeWidth, style.Stroke)
+
+			err := canvas.text(style.Letter)
+			if err != nil {
+				return err
+			}
+			if dot := style.Dot; dot != nil {
+				// The outline is centered on the edge of the dot, as in SVG
+				canvas.fillCircle(dot.X, dot.Y, dot.Radius+dot.OutlineWidth/2, "#000000")
+				canvas.fillCircle(dot.X, dot.Y, dot.Radius-dot.OutlineWidth/2, dot.Color)
+			}
+			for _, text := range []*hexText{style.Dice, style.Label} {
+				err = canvas.text(text)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// rasterCanvas draws shapes given in layout units onto an image
+type rasterCanvas struct {
+	img              *image.RGBA
+	originX, originY float64 // Position of the layout's top left in the image
+	font             *opentype.Font
+	faces            map[float64]font.Face // Faces of font by size
+}
+
+// shape is the outline of a filled area, as a list of closed paths
+type shape [][]grid.Point
+
+// fill draws a shape in the given color. Only the part of the image under
+// the shape's bounds is rasterized, which keeps large maps fast.
+func (c *rasterCanvas) fill(paths shape, hex string) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, path := range paths {
+		for _, p := range path {
+			minX, minY = math.Min(minX, p.X), math.Min(minY, p.Y)
+			maxX, maxY = math.Max(maxX, p.X), math.Max(maxY, p.Y)
+		}
+	}
+	bounds := image.Rect(
+		int(math.Floor(minX+c.originX)), int(math.Floor(minY+c.originY)),
+		int(math.Ceil(maxX+c.originX)), int(math.Ceil(maxY+c.originY)),
+	)
+	bounds = bounds.Intersect(c.img.Bounds())
+	if bounds.Empty() {
+		return
+	}
+
+	// The rasterizer covers the shape's bounds, whose top left is its origin
+	offsetX, offsetY := c.originX-float64(bounds.Min.X), c.originY-float64(bounds.Min.Y)
+	z := vector.NewRasterizer(bounds.Dx(), bounds.Dy())
+	for _, path := range paths {
+		for i, p := range path {
+			x, y := float32(p.X+offsetX), float32(p.Y+offsetY)
+			if i == 0 {
+				z.MoveTo(x, y)
+			} else {
+				z.LineTo(x, y)
+			}
+		}
+		z.ClosePath()
+	}
+
+	r, g, b := hexToRGB(hex)
+	src := image.NewUniform(color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 0xFF})
+	z.Draw(c.img, bounds, src, image.Point{})
+}
+
+// fillPolygon fills the inside of a polygon
+func (c *rasterCanvas) fillPolygon(points []grid.Point, hex string) {
+	c.fill(shape{points}, hex)
+}
+
+// strokePolygon draws the outline of a polygon, centered on its edges, as
+// each edge filled as a band with the ends of the neighboring bands meeting
+// at the corners
+func (c *rasterCanvas) strokePolygon(points []grid.Point, width float64, hex string) {
+	half := width / 2
+	var paths shape
+	for i, a := range points {
+		b := points[(i+1)%len(points)]
+		length := math.Hypot(b.X-a.X, b.Y-a.Y)
+		if length == 0 {
+			continue
+		}
+		// Offset across the edge, and along it so the bands overlap at the corners
+		nx, ny := -(b.Y-a.Y)/length*half, (b.X-a.X)/length*half
+		dx, dy := (b.X-a.X)/length*half, (b.Y-a.Y)/length*half
+		// All bands wind the same way, so overlaps are filled once
+		paths = append(paths, []grid.Point{
+			{X: a.X - dx + nx, Y: a.Y - dy + ny},
+			{X: b.X + dx + nx, Y: b.Y + dy + ny},
+			{X: b.X + dx - nx, Y: b.Y + dy - ny},
+			{X: a.X - dx - nx, Y: a.Y - dy - ny},
+		})
+	}
+	c.fill(paths, hex)
+}
+
+// circleSegments is the number of straight segments circles are drawn with
+const circleSegments = 64
+
+// fillCircle fills a circle
+func (c *rasterCanvas) fillCircle(x, y, radius float64, hex string) {
+	if radius <= 0 {
+		return
+	}
+	points := make([]grid.Point, circleSegments)
+	for i := range points {
+		angle := 2 * math.Pi * float64(i) / circleSegments
+		points[i] = grid.Point{X: x + radius*math.Cos(angle), Y: y + radius*math.Sin(angle)}
+	}
+	c.fill(shape{points}, hex)
+}
+
+// text draws a piece of text in the Go font, which stands in for the SVG's
+// Arial so images look the same on every system. Nothing is drawn for nil.
+func (c *rasterCanvas) text(text *hexText) error {
+	if text == nil {
+		return nil
+	}
+	face, err := c.face(text.FontSize)
+	if err != nil {
+		return err
+	}
+
+	r, g, b := hexToRGB(text.Color)
+	drawer := font.Drawer{
+		Dst:  c.img,
+		Src:  image.NewUniform(color.RGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 0xFF}),
+		Face: face,
+	}
+	x := text.X + c.originX
+	if text.Centered {
+		// Fixed point numbers have 6 fractional bits
+		x -= float64(drawer.MeasureString(text.Text)) / 64 / 2
+	}
+	drawer.Dot = fixed.Point26_6{X: fixed.Int26_6(x * 64), Y: fixed.Int26_6((text.Y + c.originY) * 64)}
+	drawer.DrawString(text.Text)
+	return nil
+}
+
+// face returns the Go font at a size in pixels, loading each size once
+func (c *rasterCanvas) face(size float64) (font.Face, error) {
+	if face, ok := c.faces[size]; ok {
+		return face, nil
+	}
+	if c.font == nil {
+		f, err := opentype.Parse(goregular.TTF)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load font: %w", err)
+		}
+		c.font = f
+	}
+	// At 72 DPI a point is a pixel
+	face, err := opentype.NewFace(c.font, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load font: %w", err)
+	}
+	c.faces[size] = face
+	return face, nil
+}
@@ -0,0 +1,563 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	"hexgrid/grid"
+	"hexgrid/spec"
+)
+
+func testGrid(t *testing.T) *grid.HexGrid {
+	t.Helper()
+	config := &spec.Spec{
+		Default: "#F5F5DC",
+		Seed:    99,
+		Items: []spec.ItemType{
+			{Name: "Forest", Percentage: 50, Style: "fill", Color: "#228B22"},
+			{Name: "Village", Percentage: 20, Style: "dot", Color: "#FFD700", Dice: "2d6", Letter: "V"},
+		},
+	}
+	g := grid.New(6, 4, config)
+	if err := g.Populate(rand.New(rand.NewSource(g.Seed))); err != nil {
+		t.Fatalf("Failed to populate grid: %v", err)
+	}
+	return g
+}
+
+func TestRenderers(t *testing.T) {
+	tests := []struct {
+		name     string
+		renderer func(io.Writer, *grid.HexGrid) error
+		prefix   string
+	}{
+		{"SVG", SVG, "<?xml"},
+		{"HTML", HTML, "<!DOCTYPE html>"},
+		{"PDF", PDF, "%PDF-"},
+		{"PNG", func(w io.Writer, g *grid.HexGrid) error { return PNG(w, g, RasterOptions{}) }, "\x89PNG"},
+		{"JPEG", func(w io.Writer, g *grid.HexGrid) error { return JPEG(w, g, RasterOptions{}) }, "\xff\xd8"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := tt.renderer(&buf, testGrid(t)); err != nil {
+			t.Fatalf("%s: failed to render: %v", tt.name, err)
+		}
+		if !strings.HasPrefix(buf.String(), tt.prefix) {
+			t.Errorf("%s: expected output to start with %q", tt.name, tt.prefix)
+		}
+	}
+}
+
+func TestPDFTiles(t *testing.T) {
+	tiles, cols, rows := pdfTiles(100, 50, 200, 150, tileOverlap)
+	if len(tiles) != 1 || cols != 1 || rows != 1 {
+		t.Errorf("Expected a small map to fit on one page, got %dx%d pages", cols, rows)
+	}
+
+	tiles, cols, rows = pdfTiles(500, 250, 200, 150, tileOverlap)
+	if cols != 3 || rows != 2 || len(tiles) != 6 {
+		t.Fatalf("Expected 3x2 pages, got %dx%d (%d tiles)", cols, rows, len(tiles))
+	}
+	for _, tl := range tiles {
+		// Neighboring pages share tileOverlap mm of the map, and together
+		// the pages cover all of it
+		if tl.X != float64(tl.Col)*(200-tileOverlap) || tl.Y != float64(tl.Row)*(150-tileOverlap) {
+			t.Errorf("Page %s starts at %g,%g", tileLabel(tl.Col, tl.Row), tl.X, tl.Y)
+		}
+	}
+	last := tiles[len(tiles)-1]
+	if last.X+200 < 500 || last.Y+150 < 250 {
+		t.Errorf("Pages don't cover the map: last page ends at %g,%g", last.X+200, last.Y+150)
+	}
+
+	for _, tt := range []struct {
+		col, row int
+		label    string
+	}{
+		{0, 0, "A1"}, {2, 1, "C2"}, {25, 9, "Z10"}, {26, 0, "AA1"}, {27, 2, "AB3"},
+	} {
+		if label := tileLabel(tt.col, tt.row); label != tt.label {
+			t.Errorf("Expected page %d,%d to be labelled %s, got %s", tt.col, tt.row, tt.label, label)
+		}
+	}
+}
+
+func TestPDFLargeGrid(t *testing.T) {
+	config := &spec.Spec{
+		Default: "#F5F5DC",
+		Items:   []spec.ItemType{{Name: "Star", Percentage: 30, Style: "dot", Color: "#FFD700", Dice: "1d6"}},
+	}
+	g := grid.New(80, 40, config)
+	if err := g.Populate(rand.New(rand.NewSource(1))); err != nil {
+		t.Fatalf("Failed to populate grid: %v", err)
+	}
+
+	var small, large bytes.Buffer
+	if err := PDF(&small, testGrid(t)); err != nil {
+		t.Fatalf("Failed to render small grid: %v", err)
+	}
+	if err := PDF(&large, g); err != nil {
+		t.Fatalf("Failed to render large grid: %v", err)
+	}
+	if !bytes.Contains(small.Bytes(), []byte("/Count 1\n")) {
+		t.Error("Expected the small grid on a single page")
+	}
+	// 4x4 pages of map plus the legend page
+	if !bytes.Contains(large.Bytes(), []byte("/Count 17\n")) {
+		t.Error("Expected the large grid to be tiled across 17 pages")
+	}
+}
+
+func TestPDFPageSettings(t *testing.T) {
+	config := &spec.Spec{
+		Default: "#F5F5DC",
+		Items:   []spec.ItemType{{Name: "Star", Percentage: 30, Style: "dot", Color: "#FFD700"}},
+	}
+	g := grid.New(80, 40, config)
+
+	tests := []struct {
+		name   string
+		page   spec.PageSettings
+		expect string
+	}{
+		{"fit", spec.PageSettings{Fit: true}, "/Count 1\n"},
+		{"letter portrait", spec.PageSettings{Size: "Letter", Orientation: "portrait", Fit: true}, "/MediaBox [0 0 612.00 792.00]"},
+		{"tabloid landscape", spec.PageSettings{Size: "Tabloid", Fit: true}, "/MediaBox [0 0 1224.00 792.00]"},
+	}
+	for _, tt := range tests {
+		g.Page = tt.page
+		var buf bytes.Buffer
+		if err := PDF(&buf, g); err != nil {
+			t.Fatalf("%s: failed to render: %v", tt.name, err)
+		}
+		if !strings.Contains(buf.String(), tt.expect) {
+			t.Errorf("%s: expected PDF to contain %q", tt.name, tt.expect)
+		}
+	}
+
+	// One-inch hexes are tiled across the pages their size needs, plus the
+	// legend page
+	g.Page = spec.PageSettings{HexSize: "1in"}
+	page, err := newPDFPage(g)
+	if err != nil {
+		t.Fatalf("Failed to lay out 1in hexes: %v", err)
+	}
+	layout := g.Layout(page.size)
+	pages := tileCount(layout.Width, page.areaWidth(), page.overlap)*tileCount(layout.Height, page.areaHeight(), page.overlap) + 1
+	if pages <= 17 {
+		t.Fatalf("Expected 1in hexes to need more pages than the default hex size, got %d", pages)
+	}
+	var buf bytes.Buffer
+	if err := PDF(&buf, g); err != nil {
+		t.Fatalf("Failed to render 1in hexes: %v", err)
+	}
+	if want := fmt.Sprintf("/Count %d\n", pages); !strings.Contains(buf.String(), want) {
+		t.Errorf("Expected 1in hexes on %d pages", pages)
+	}
+
+	g.Page = spec.PageSettings{Size: "B5"}
+	if err := PDF(io.Discard, g); err == nil || !strings.Contains(err.Error(), "invalid page size") {
+		t.Errorf("Expected an invalid page size error, got %v", err)
+	}
+}
+
+func TestPDFLegendSpace(t *testing.T) {
+	config := &spec.Spec{
+		Default: "#F5F5DC",
+		Items:   []spec.ItemType{{Name: "Star", Percentage: 30, Style: "dot", Color: "#FFD700"}},
+	}
+	tests := []struct {
+		name       string
+		rows, cols int
+		page       spec.PageSettings
+		legendPage bool
+	}{
+		{"fitted", 25, 10, spec.PageSettings{Size: "A4", Orientation: "landscape", Fit: true}, false},
+		{"fitted tall", 10, 40, spec.PageSettings{Size: "A4", Orientation: "landscape", Fit: true}, false},
+		{"beside the legend", 6, 6, spec.PageSettings{}, false},
+		{"full width", 8, 8, spec.PageSettings{}, true},
+	}
+	for _, tt := range tests {
+		g := grid.New(tt.rows, tt.cols, config)
+		g.Page = tt.page
+		page, err := newPDFPage(g)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		layout := g.Layout(page.size)
+		if tiles, _, _ := pdfTiles(layout.Width, layout.Height, page.areaWidth(), page.areaHeight(), page.overlap); len(tiles) != 1 {
+			t.Fatalf("%s: expected a single page map, got %d pages", tt.name, len(tiles))
+		}
+
+		// The map's bounding box stays clear of the legend's column
+		x, _, legendPage := page.placeMap(layout.Width, layout.Height)
+		if legendPage != tt.legendPage {
+			t.Errorf("%s: expected the legend on its own page to be %v", tt.name, tt.legendPage)
+		}
+		if !legendPage && x+layout.Width > page.legendX()-legendGap+1e-9 {
+			t.Errorf("%s: map from x %.1f to %.1f runs under the legend at %.1f", tt.name, x, x+layout.Width, page.legendX())
+		}
+		if x < page.margin-1e-9 {
+			t.Errorf("%s: map starts at x %.1f, inside the margin", tt.name, x)
+		}
+
+		var buf bytes.Buffer
+		if err := PDF(&buf, g); err != nil {
+			t.Fatalf("%s: failed to render: %v", tt.name, err)
+		}
+		want := "/Count 1\n"
+		if tt.legendPage {
+			want = "/Count 2\n"
+		}
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("%s: expected PDF to contain %q", tt.name, want)
+		}
+	}
+}
+
+func TestSVGUsesLayout(t *testing.T) {
+	for _, layout := range []spec.HexLayout{{}, {Orientation: spec.OrientationPointy, Offset: spec.OffsetEvenR}} {
+		g := grid.New(5, 4, &spec.Spec{Default: "#FFFFFF", Layout: layout})
+		var buf bytes.Buffer
+		if err := SVG(&buf, g); err != nil {
+			t.Fatalf("Failed to render: %v", err)
+		}
+
+		// Every hexagon is drawn at the corners given by the grid's layout
+		hexLayout := g.Layout(HexSize)
+		for _, cells := range g.Cells {
+			for _, cell := range cells {
+				if path := generateHexagonPath(hexLayout.Corners(cell)); !strings.Contains(buf.String(), `d="`+path+`"`) {
+					t.Errorf("%v: expected hexagon %d,%d at %s", layout, cell.Row, cell.Col, path)
+				}
+			}
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	g := testGrid(t)
+	g.Labels = spec.LabelSettings{Format: spec.LabelA1, Position: spec.LabelBottom}
+
+	var svg bytes.Buffer
+	if err := SVG(&svg, g); err != nil {
+		t.Fatalf("Failed to render SVG: %v", err)
+	}
+	for _, label := range []string{">A1</text>", ">H3</text>"} {
+		if !strings.Contains(svg.String(), label) {
+			t.Errorf("Expected label %s in SVG", label)
+		}
+	}
+
+	// Labels sit below the center of the hex
+	layout := g.Layout(HexSize)
+	label, ok := labelFor(g, layout, g.Cells[0][0])
+	if !ok || label.Y <= layout.Center(g.Cells[0][0]).Y {
+		t.Errorf("Expected a label below the center of the hex, got %+v", label)
+	}
+
+	if err := PDF(io.Discard, g); err != nil {
+		t.Errorf("Failed to render PDF with labels: %v", err)
+	}
+}
+
+func TestStyleFor(t *testing.T) {
+	config := &spec.Spec{
+		Default: "#F5F5DC",
+		Items: []spec.ItemType{
+			{Name: "Giant", Percentage: 50, Style: "dot", Color: "#FF0000", Letter: "G", Size: "xx-large", Dice: "1d6"},
+			{Name: "Dwarf", Percentage: 50, Style: "dot", Color: "#FFFFFF", Size: "small"},
+		},
+	}
+	g := grid.New(4, 4, config)
+	if err := g.Populate(rand.New(rand.NewSource(5))); err != nil {
+		t.Fatalf("Failed to populate grid: %v", err)
+	}
+
+	// The SVG sizes, in pixels for 25 pixel hexes, scale with the hex size
+	svgRadii := map[string]float64{"Giant": 17, "Dwarf": 6}
+	for _, size := range []float64{HexSize, 8} {
+		layout := g.Layout(size)
+		for _, cells := range g.Cells {
+			for _, cell := range cells {
+				style := styleFor(g, layout, cell)
+				if style.Dot == nil || style.Dot.Color != cell.ItemType.Color {
+					t.Fatalf("Expected a %s dot for %s", cell.ItemType.Color, cell.ItemType.Name)
+				}
+				scale := size / HexSize
+				if math.Abs(style.Dot.Radius-svgRadii[cell.ItemType.Name]*scale) > 1e-9 || math.Abs(style.Dot.OutlineWidth-2*scale) > 1e-9 {
+					t.Errorf("%s at size %g: got dot radius %g with outline %g", cell.ItemType.Name, size, style.Dot.Radius, style.Dot.OutlineWidth)
+				}
+				if (style.Letter != nil) != (cell.ItemType.Letter != "") || (style.Dice != nil) != (cell.DiceResult != nil) {
+					t.Errorf("%s: letter or dice result missing from style", cell.ItemType.Name)
+				}
+			}
+		}
+	}
+
+	if err := PDF(io.Discard, g); err != nil {
+		t.Errorf("Failed to render PDF: %v", err)
+	}
+}
+
+func TestRaster(t *testing.T) {
+	g := testGrid(t)
+	layout := g.Layout(HexSize)
+	svgWidth, svgHeight := layout.Width+2*SVGMargin, layout.Height+2*SVGMargin
+
+	// At 96 DPI the image is the size of the SVG, and twice that at 192
+	for _, scale := range []float64{1, 2} {
+		img, err := Raster(g, RasterOptions{DPI: 96 * scale}, color.White)
+		if err != nil {
+			t.Fatalf("Failed to rasterize: %v", err)
+		}
+		size := img.Bounds().Size()
+		if size.X != int(math.Ceil(svgWidth*scale)) || size.Y != int(math.Ceil(svgHeight*scale)) {
+			t.Errorf("At scale %g expected a %gx%g image, got %v", scale, svgWidth*scale, svgHeight*scale, size)
+		}
+
+		// Each hexagon is filled at its center, as in the SVG
+		for _, row := range g.Cells {
+			for _, cell := range row {
+				if cell.ItemType != nil && cell.ItemType.Style != "fill" {
+					continue
+				}
+				style := styleFor(g, layout, cell)
+				center := layout.Center(cell)
+				x, y := int((center.X+SVGMargin)*scale), int((center.Y+SVGMargin)*scale)
+				r, gr, b := hexToRGB(style.Fill)
+				want := color.RGBA{R: uint8(r), G: uint8(gr), B: uint8(b), A: 0xFF}
+				if got := img.RGBAAt(x, y); got != want {
+					t.Errorf("At scale %g cell %d,%d is %v at its center, expected %v", scale, cell.Row, cell.Col, got, want)
+				}
+			}
+		}
+	}
+
+	img, err := Raster(g, RasterOptions{Width: 250}, color.White)
+	if err != nil {
+		t.Fatalf("Failed to rasterize: %v", err)
+	}
+	if size := img.Bounds().Size(); size.X != 250 || math.Abs(float64(size.Y)-250*svgHeight/svgWidth) > 1 {
+		t.Errorf("Expected a 250 pixel wide image in the SVG's proportions, got %v", size)
+	}
+
+	// DrawRaster shows the part of the map under an image placed anywhere
+	// on it, matching the whole image
+	full, err := Raster(g, RasterOptions{}, color.White)
+	if err != nil {
+		t.Fatalf("Failed to rasterize: %v", err)
+	}
+	part := image.NewRGBA(image.Rect(0, 0, 60, 40))
+	draw.Draw(part, part.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	if err := DrawRaster(part, g, 1, grid.Point{X: -50, Y: -70}); err != nil {
+		t.Fatalf("Failed to draw: %v", err)
+	}
+	for _, p := range []image.Point{{0, 0}, {30, 20}, {59, 39}} {
+		if got, want := part.RGBAAt(p.X, p.Y), full.RGBAAt(p.X+50, p.Y+70); got != want {
+			t.Errorf("Pixel %v is %v, expected %v as in the whole image", p, got, want)
+		}
+	}
+
+	for _, opts := range []RasterOptions{{DPI: -1}, {Width: -10}} {
+		if _, err := Raster(g, opts, color.White); err == nil {
+			t.Errorf("Expected %+v to fail", opts)
+		}
+	}
+
+	// Images too large to draw in memory are refused before any is made
+	for _, opts := range []RasterOptions{{DPI: 1e6}, {Width: 10000000}, {Width: maxRasterSide + 1}, {DPI: 1e300}} {
+		if _, err := Raster(g, opts, color.White); err == nil || !strings.Contains(err.Error(), "too large") {
+			t.Errorf("Expected %+v to be too large, got %v", opts, err)
+		}
+	}
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestWriteErrors(t *testing.T) {
+	for name, renderer := range map[string]func(io.Writer, *grid.HexGrid) error{"SVG": SVG, "HTML": HTML} {
+		err := renderer(failingWriter{}, testGrid(t))
+		if err == nil || !strings.Contains(err.Error(), "disk full") {
+			t.Errorf("%s: expected the write error, got %v", name, err)
+		}
+	}
+}
+
+// benchmarkGrid returns a populated grid of the given size
+func benchmarkGrid(b *testing.B, rows, cols int) *grid.HexGrid {
+	b.Helper()
+	config := &spec.Spec{
+		Default: "#F5F5DC",
+		Items: []spec.ItemType{
+			{Name: "Forest", Percentage: 40, Style: "fill", Color: "#228B22", Letter: "F"},
+			{Name: "Village", Percentage: 10, Style: "dot", Color: "#FFD700", Dice: "2d6"},
+		},
+		Labels: spec.LabelSettings{Format: spec.LabelXXYY},
+	}
+	g := grid.New(rows, cols, config)
+	if err := g.Populate(rand.New(rand.NewSource(1))); err != nil {
+		b.Fatalf("Failed to populate grid: %v", err)
+	}
+	return g
+}
+
+// The renderers write each cell as it is drawn, so the time per cell stays
+// the same as grids grow
+func BenchmarkSVG(b *testing.B) {
+	for _, size := range []int{50, 100, 250, 500} {
+		g := benchmarkGrid(b, size, size)
+		for _, r := range []struct {
+			name     string
+			renderer func(io.Writer, *grid.HexGrid) error
+		}{{"SVG", SVG}, {"HTML", HTML}} {
+			b.Run(fmt.Sprintf("%s/%dx%d", r.name, size, size), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					if err := r.renderer(io.Discard, g); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*size*size), "ns/cell")
+			})
+		}
+	}
+}
+
+func TestHostileNames(t *testing.T) {
+	config := &spec.Spec{
+		Default: "#F5F5DC",
+		Items: []spec.ItemType{
+			{Name: "Tom & Jerry's <Keep>", Percentage: 40, Style: "fill", Color: "#228B22", Letter: "<&"},
+			{Name: `<script>alert("x")</script>`, Percentage: 40, Style: "dot", Color: "#FFD700", Letter: `"'>`},
+		},
+	}
+	g := grid.New(4, 4, config)
+	if err := g.Populate(rand.New(rand.NewSource(1))); err != nil {
+		t.Fatalf("Failed to populate grid: %v", err)
+	}
+
+	var svg, html bytes.Buffer
+	if err := SVG(&svg, g); err != nil {
+		t.Fatalf("Failed to render SVG: %v", err)
+	}
+	if err := HTML(&html, g); err != nil {
+		t.Fatalf("Failed to render HTML: %v", err)
+	}
+
+	// The SVG is well-formed XML and its text is the letters as written
+	var letters []string
+	decoder := xml.NewDecoder(bytes.NewReader(svg.Bytes()))
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("SVG is not valid XML: %v", err)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			inText = token.Name.Local == "text"
+		case xml.CharData:
+			if inText {
+				letters = append(letters, string(token))
+			}
+		case xml.EndElement:
+			inText = false
+		}
+	}
+	for _, letter := range []string{"<&", `"'>`} {
+		if !slices.Contains(letters, letter) {
+			t.Errorf("Expected the letter %q in the SVG text, got %q", letter, letters)
+		}
+	}
+
+	for _, page := range []string{svg.String(), html.String()} {
+		if strings.Contains(page, "<script>alert") || strings.Contains(page, "<Keep>") {
+			t.Errorf("Item names are not escaped:\n%s", page)
+		}
+	}
+	if !strings.Contains(html.String(), "Tom &amp; Jerry&#39;s &lt;Keep&gt;") {
+		t.Errorf("Expected the escaped item name in the HTML legend")
+	}
+}
+
+func TestHTMLViewer(t *testing.T) {
+	g := testGrid(t)
+	g.Labels.Format = spec.LabelA1
+	var buf bytes.Buffer
+	if err := HTML(&buf, g); err != nil {
+		t.Fatalf("Failed to render HTML: %v", err)
+	}
+	page := buf.String()
+
+	// The page works offline
+	for _, external := range []string{`src="http`, `href="http`, "@import", "url("} {
+		if strings.Contains(page, external) {
+			t.Errorf("Expected a self-contained page, found %q", external)
+		}
+	}
+
+	// Every hex is a group holding its coordinate, item and dice roll
+	if n := strings.Count(page, `<g class="cell"`); n != g.Rows*g.Cols {
+		t.Errorf("Expected %d cell groups, got %d", g.Rows*g.Cols, n)
+	}
+	for _, row := range g.Cells {
+		for _, cell := range row {
+			group := fmt.Sprintf(`<g class="cell" data-label="%s"`, g.Label(cell))
+			if cell.ItemType != nil {
+				group += fmt.Sprintf(` data-item="%d"`, slices.Index(g.ItemTypes, cell.ItemType))
+			}
+			if cell.DiceResult != nil {
+				group += fmt.Sprintf(` data-dice="%s = %d"`, cell.DiceResult.Breakdown, cell.DiceResult.Total)
+			}
+			if !strings.Contains(page, group+">") {
+				t.Errorf("Expected cell %d,%d in a group %s>", cell.Row, cell.Col, group)
+			}
+		}
+	}
+
+	// The item types are embedded for the script, in the order of data-item
+	start := strings.Index(page, `<script type="application/json" id="grid-data">`)
+	if start < 0 {
+		t.Fatal("Expected the embedded grid data")
+	}
+	dataJSON := page[start:]
+	dataJSON = dataJSON[strings.Index(dataJSON, ">")+1 : strings.Index(dataJSON, "</script>")]
+	var data viewerData
+	if err := json.Unmarshal([]byte(dataJSON), &data); err != nil {
+		t.Fatalf("Failed to parse grid data: %v", err)
+	}
+	if data.Seed != g.Seed || len(data.Items) != len(g.ItemTypes) {
+		t.Fatalf("Unexpected grid data %+v", data)
+	}
+	for i, item := range data.Items {
+		if item.Name != g.ItemTypes[i].Name || item.Color != g.ItemTypes[i].Color {
+			t.Errorf("Item %d is %+v, expected %s", i, item, g.ItemTypes[i].Name)
+		}
+	}
+
+	// Each item type has a toggle in the legend
+	for i := range g.ItemTypes {
+		if !strings.Contains(page, fmt.Sprintf(`class="legend-toggle" data-item="%d" checked`, i)) {
+			t.Errorf("Expected a legend toggle for item %d", i)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package render
+
+import (
+	"fmt"
+
+	"hexgrid/grid"
+	"hexgrid/spec"
+)
+
+// Proportions of the drawing of a hex, relative to the hex size (the
+// distance from its center to a corner)
+const (
+	hexStrokeWidth  = 0.04 // outline of the hexagon
+	dotOutlineWidth = 0.08 // black outline of dots
+	textSize        = 0.4  // item letters and dice results
+)
+
+// dotRadii are the radii of dots for each spec.Sizes value; other sizes are "medium"
+var dotRadii = map[string]float64{
+	"small":    0.24,
+	"medium":   0.36,
+	"large":    0.48,
+	"x-large":  0.56,
+	"xx-large": 0.68,
+}
+
+// hexStyle is everything drawn for one cell, placed by a layout. SVG, PDF
+// and images all draw from it, in the order of the fields, so the outputs match.
+type hexStyle struct {
+	Corners     [6]grid.Point
+	Fill        string
+	Stroke      string
+	StrokeWidth float64
+
+	Letter *hexText // item letter, starting at the center
+	Dot    *hexDot
+	Dice   *hexText // dice result at the left of the hex
+	Label  *hexText // coordinate label
+}
+
+// hexDot is the dot drawn for "dot" style items
+type hexDot struct {
+	X, Y         float64
+	Radius       float64
+	Color        string
+	OutlineWidth float64 // the outline is black
+}
+
+// hexText is a piece of text drawn on a hex
+type hexText struct {
+	Text     string
+	X, Y     float64 // Start of the baseline, or its middle for centered text
+	FontSize float64 // Text height in layout units
+	Color    string
+	Centered bool
+}
+
+// styleFor resolves how cell is drawn in layout
+func styleFor(g *grid.HexGrid, layout grid.Layout, cell *grid.HexCell) hexStyle {
+	size := layout.Size
+	center := layout.Center(cell)
+	style := hexStyle{
+		Corners:     layout.Corners(cell),
+		Fill:        g.DefaultColor,
+		Stroke:      "#CCCCCC",
+		StrokeWidth: hexStrokeWidth * size,
+	}
+
+	if item := cell.ItemType; item != nil {
+		style.Stroke = "#333333"
+		if item.Style == "fill" {
+			style.Fill = item.Color
+		}
+
+		if item.Letter != "" {
+			style.Letter = &hexText{Text: item.Letter, X: center.X, Y: center.Y, FontSize: textSize * size, Color: "#000000"}
+		}
+
+		if item.Style == "dot" {
+			radius, ok := dotRadii[item.Size]
+			if !ok {
+				radius = dotRadii["medium"]
+			}
+			style.Dot = &hexDot{
+				X:            center.X,
+				Y:            center.Y,
+				Radius:       radius * size,
+				Color:        item.Color,
+				OutlineWidth: dotOutlineWidth * size,
+			}
+		}
+	}
+
+	if cell.DiceResult != nil {
+		style.Dice = &hexText{
+			Text:     fmt.Sprintf("%d", cell.DiceResult.Total),
+			X:        center.X - 0.8*size,
+			Y:        center.Y + 0.16*size,
+			FontSize: textSize * size,
+			Color:    "#000000",
+		}
+	}
+
+	if label, ok := labelFor(g, layout, cell); ok {
+		style.Label = &label
+	}
+
+	return style
+}
+
+// labelFor returns the coordinate label to draw on cell, or false if the
+// grid has no labels
+func labelFor(g *grid.HexGrid, layout grid.Layout, cell *grid.HexCell) (hexText, bool) {
+	if g.Labels.Format == "" {
+		return hexText{}, false
+	}
+
+	fontSize := g.Labels.FontSize
+	if fontSize == 0 {
+		fontSize = spec.DefaultLabelFontSize
+	}
+	fontSize *= layout.Size
+
+	center := layout.Center(cell)
+	y := center.Y
+	switch g.Labels.Position {
+	case spec.LabelCenter:
+	case spec.LabelBottom:
+		y += 0.6 * layout.Size
+	default:
+		y -= 0.6 * layout.Size
+	}
+
+	// Capitals and digits are about 0.7 of the font size tall, so this
+	// centers them on y
+	return hexText{
+		Text:     g.Label(cell),
+		X:        center.X,
+		Y:        y + 0.35*fontSize,
+		FontSize: fontSize,
+		Color:    "#555555",
+		Centered: true,
+	}, true
+}
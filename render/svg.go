@@ -0,0 +1,159 @@
+// Package render draws hex grids as SVG, HTML and PDF documents and PNG and
+// JPEG images.
+package render
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"hexgrid/grid"
+	"hexgrid/spec"
+)
+
+// Hexagon parameters
+const (
+	HexSize   = 25.0 // Distance from center to any corner
+	SVGMargin = 20.0 // Space around the map
+)
+
+// SVG writes an SVG representation of the hex grid to w. The document is
+// written as it is generated, so large grids take time and memory in
+// proportion to their number of cells.
+func SVG(w io.Writer, g *grid.HexGrid) error {
+	out := bufio.NewWriter(w)
+	writeSVG(out, g, false)
+	err := out.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to write SVG: %w", err)
+	}
+	return nil
+}
+
+// SVGSize returns the width and height of the grid's SVG in pixels
+func SVGSize(g *grid.HexGrid) (width, height float64) {
+	layout := g.Layout(HexSize)
+	return layout.Width + 2*SVGMargin, layout.Height + 2*SVGMargin
+}
+
+// writeSVG writes the SVG document to out, whose Flush reports any write
+// error. An interactive SVG is embedded in the HTML viewer: it fills its
+// container and groups the elements of each cell with the cell's data.
+func writeSVG(out *bufio.Writer, g *grid.HexGrid, interactive bool) {
+	layout := g.Layout(HexSize)
+	width, height := SVGSize(g)
+
+	// Start SVG content
+	if interactive {
+		fmt.Fprintf(out, `<svg id="map" width="100%%" height="100%%" viewBox="0 0 %.1f %.1f" xmlns="http://www.w3.org/2000/svg">`, width, height)
+	} else {
+		fmt.Fprintf(out, `<?xml version="1.0" encoding="UTF-8"?>
+<svg width="%.1f" height="%.1f" xmlns="http://www.w3.org/2000/svg">`, width, height)
+	}
+	fmt.Fprintf(out, `
+  <desc>Hex grid %dx%d, seed %d</desc>
+  <defs>
+    <style>
+      .hexagon { stroke: #333; stroke-width: 1; }
+      .hexagon-dot { fill: none; }
+    </style>
+  </defs>
+  <g transform="translate(%.0f, %.0f)">`, g.Rows, g.Cols, g.Seed, SVGMargin, SVGMargin)
+
+	itemIndex := make(map[*spec.ItemType]int, len(g.ItemTypes))
+	for i, itemType := range g.ItemTypes {
+		itemIndex[itemType] = i
+	}
+
+	// Generate hexagons
+	for row := 0; row < g.Rows; row++ {
+		for col := 0; col < g.Cols; col++ {
+			cell := g.Cells[row][col]
+			style := styleFor(g, layout, cell)
+			if interactive {
+				writeCellGroup(out, g, cell, itemIndex)
+			}
+
+			// Add hexagon with direct color attributes
+			fmt.Fprintf(out, `
+    <path d="%s" fill="%s" stroke="%s" stroke-width="%.1f"/>`, generateHexagonPath(style.Corners), escapeXML(style.Fill), escapeXML(style.Stroke), style.StrokeWidth)
+
+			// Add the letter, the dot with its black outline, the dice result and the label
+			writeSVGText(out, style.Letter, "")
+			if dot := style.Dot; dot != nil {
+				fmt.Fprintf(out, `
+    <circle cx="%.1f" cy="%.1f" r="%.1f" fill="%s" stroke="black" stroke-width="%.1f"/>`, dot.X, dot.Y, dot.Radius, escapeXML(dot.Color), dot.OutlineWidth)
+			}
+			writeSVGText(out, style.Dice, "")
+			writeSVGText(out, style.Label, "label")
+			if interactive {
+				out.WriteString(`
+    </g>`)
+			}
+		}
+	}
+
+	out.WriteString(`
+  </g>
+</svg>`)
+}
+
+// writeCellGroup opens the group of an interactive cell's elements, holding
+// the cell's label, item (an index into the grid's item types) and dice roll
+func writeCellGroup(out *bufio.Writer, g *grid.HexGrid, cell *grid.HexCell, itemIndex map[*spec.ItemType]int) {
+	fmt.Fprintf(out, `
+    <g class="cell" data-label="%s"`, escapeXML(g.Label(cell)))
+	if cell.ItemType != nil {
+		fmt.Fprintf(out, ` data-item="%d"`, itemIndex[cell.ItemType])
+	}
+	if cell.DiceResult != nil {
+		fmt.Fprintf(out, ` data-dice="%s = %d"`, escapeXML(cell.DiceResult.Breakdown), cell.DiceResult.Total)
+	}
+	out.WriteString(">")
+}
+
+// writeSVGText writes an SVG text element, or nothing for nil. The class is
+// left out when empty.
+func writeSVGText(out *bufio.Writer, text *hexText, class string) {
+	if text == nil {
+		return
+	}
+	anchor := "start"
+	if text.Centered {
+		anchor = "middle"
+	}
+	if class != "" {
+		class = fmt.Sprintf(` class="%s"`, class)
+	}
+	fmt.Fprintf(out, `
+    <text%s x="%.1f" y="%.1f" font-family="Arial, sans-serif, condensed" font-size="%.1f" fill="%s" text-anchor="%s">%s</text>`,
+		class, text.X, text.Y, text.FontSize, escapeXML(text.Color), anchor, escapeXML(text.Text))
+}
+
+// escapeXML escapes text for XML and HTML text and attribute values. Every
+// string from a spec goes through it, so item names such as "Fish & Chips"
+// or "<script>" show as written instead of breaking the document.
+func escapeXML(s string) string {
+	var b strings.Builder
+	// Writing to a strings.Builder can't fail
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// generateHexagonPath creates the SVG path for a hexagon
+func generateHexagonPath(corners [6]grid.Point) string {
+	var points []string
+
+	for i, corner := range corners {
+		if i == 0 {
+			points = append(points, fmt.Sprintf("M %.1f %.1f", corner.X, corner.Y))
+		} else {
+			points = append(points, fmt.Sprintf("L %.1f %.1f", corner.X, corner.Y))
+		}
+	}
+
+	points = append(points, "Z")
+	return strings.Join(points, " ")
+}
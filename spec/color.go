@@ -0,0 +1,28 @@
+package spec
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ParseColor parses a hex color of the form "#RRGGBB" or "#RGB"
+func ParseColor(s string) (r, g, b int, err error) {
+	if len(s) == 0 || s[0] != '#' {
+		return 0, 0, 0, fmt.Errorf("%q must start with '#'", s)
+	}
+
+	digits := s[1:]
+	if len(digits) == 3 {
+		// Expand the short form, so "#F80" means "#FF8800"
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	}
+	if len(digits) != 6 {
+		return 0, 0, 0, fmt.Errorf("%q must have 3 or 6 hex digits", s)
+	}
+
+	value, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("%q is not a hex color", s)
+	}
+	return int(value >> 16 & 0xFF), int(value >> 8 & 0xFF), int(value & 0xFF), nil
+}
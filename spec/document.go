@@ -0,0 +1,428 @@
+package spec
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a spec file opened for editing. Its items are changed in the
+// YAML node tree rather than re-encoded from a Spec, so comments, quoting and
+// every field the editor doesn't know about are kept when it's written back.
+// Only the items that were changed or added are encoded again; the rest of
+// the file, blank lines included, is written back as it was read.
+type Document struct {
+	root yaml.Node
+
+	lines   [][]byte                  // Lines of the source, each with its newline
+	sources map[*yaml.Node]itemSource // Where each item read from the source is
+	edited  map[*yaml.Node]bool       // Items read from the source and since changed
+	indent  int                       // Column of the items' dashes
+	start   int                       // Line of the first item
+	end     int                       // Line after the last item
+	blank   []byte                    // Blank lines put between added items, as between the last two read
+	newKey  bool                      // The source has no items key, so one goes before added items
+}
+
+// itemSource is where an item is in the source, as line indexes: the blank
+// lines and comments that separate it from the item before, its head comment,
+// and its body from the dash to its last line
+type itemSource struct {
+	start, head, body, end int
+}
+
+// LoadDocument reads a spec file for editing
+func LoadDocument(filePath string) (*Document, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read YAML file: %w", err)
+	}
+	return ParseDocument(data)
+}
+
+// ParseDocument parses spec data for editing. Unlike Parse it doesn't
+// validate the spec, which may be half written.
+func ParseDocument(data []byte) (*Document, error) {
+	d := &Document{}
+	if err := yaml.Unmarshal(data, &d.root); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if d.root.Kind == 0 {
+		// An empty file
+		d.root = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if d.mapping().Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("spec must be a YAML mapping")
+	}
+	d.findItems(data)
+	return d, nil
+}
+
+// findItems finds the lines of each item in the source, or where items are
+// to be added to a spec without any. Items written in flow style, as in
+// "items: []", aren't looked for, and the document is then encoded whole.
+func (d *Document) findItems(data []byte) {
+	items := valueOf(d.mapping(), "items")
+	if d.mapping().Style&yaml.FlowStyle != 0 {
+		return
+	}
+	if items != nil && !isEmptyNull(items) && (items.Kind != yaml.SequenceNode || items.Style&yaml.FlowStyle != 0 || len(items.Content) == 0) {
+		return
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		data = append(data[:len(data):len(data)], '\n')
+	}
+	d.lines = bytes.SplitAfter(data, []byte("\n"))
+	d.lines = d.lines[:len(d.lines)-1] // The empty string after the last newline
+	d.sources = make(map[*yaml.Node]itemSource)
+	d.edited = make(map[*yaml.Node]bool)
+	d.indent = 2
+
+	switch {
+	case items == nil:
+		// Added items go at the end, under a new items key
+		d.start, d.end, d.newKey = len(d.lines), len(d.lines), true
+		return
+	case items.Kind != yaml.SequenceNode:
+		// Added items go right below the empty items key
+		d.start, d.end = items.Line, items.Line
+		return
+	}
+
+	start := -1
+	for i, node := range items.Content {
+		// The dash is on the line of the item's first key, or above it
+		body := node.Line - 1
+		for body > 0 && !bytes.HasPrefix(bytes.TrimSpace(d.lines[body]), []byte("-")) {
+			body--
+		}
+		indent := indentOf(d.lines[body])
+		if i == 0 {
+			d.indent = indent
+		}
+
+		// The item goes on while lines are indented past its dash, and
+		// takes in any blank lines between them
+		end := body + 1
+		for j := end; j < len(d.lines); j++ {
+			if len(bytes.TrimSpace(d.lines[j])) == 0 {
+				continue
+			}
+			if indentOf(d.lines[j]) <= indent {
+				break
+			}
+			end = j + 1
+		}
+
+		// The comment lines right above the dash are the item's head comment
+		head := body
+		for head > max(start, 0) && bytes.HasPrefix(bytes.TrimSpace(d.lines[head-1]), []byte("#")) {
+			head--
+		}
+		if i == 0 {
+			start = head
+		}
+		d.sources[node] = itemSource{start: start, head: head, body: body, end: end}
+		if i > 0 {
+			d.blank = nil
+			for _, line := range d.lines[start:head] {
+				if len(bytes.TrimSpace(line)) == 0 {
+					d.blank = append(d.blank, line...)
+				}
+			}
+		}
+		start = end
+	}
+	d.start, d.end = d.sources[items.Content[0]].start, start
+}
+
+// isEmptyNull reports whether node is a null written as nothing at all, as
+// the value of "items:"
+func isEmptyNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null" && node.Value == ""
+}
+
+// indentOf returns the number of spaces a line starts with
+func indentOf(line []byte) int {
+	return len(line) - len(bytes.TrimLeft(line, " "))
+}
+
+// mapping returns the top level mapping of the spec
+func (d *Document) mapping() *yaml.Node {
+	return d.root.Content[0]
+}
+
+// itemNodes returns the nodes of the items, or nil if there are none
+func (d *Document) itemNodes() []*yaml.Node {
+	if items := valueOf(d.mapping(), "items"); items != nil && items.Kind == yaml.SequenceNode {
+		return items.Content
+	}
+	return nil
+}
+
+// Items decodes the items of the spec
+func (d *Document) Items() ([]ItemType, error) {
+	nodes := d.itemNodes()
+	items := make([]ItemType, len(nodes))
+	for i, node := range nodes {
+		if err := node.Decode(&items[i]); err != nil {
+			return nil, fmt.Errorf("failed to decode item %d: %w", i+1, err)
+		}
+	}
+	return items, nil
+}
+
+// SetItem changes the name, quantity, style, color, dice, letter and size of
+// the i-th item. Fields whose value is unchanged keep their formatting.
+func (d *Document) SetItem(i int, item ItemType) error {
+	nodes := d.itemNodes()
+	if i < 0 || i >= len(nodes) {
+		return fmt.Errorf("no item %d", i+1)
+	}
+	if nodes[i].Kind != yaml.MappingNode {
+		return fmt.Errorf("item %d is not a mapping", i+1)
+	}
+	changed, err := setItemFields(nodes[i], item)
+	if changed && d.edited != nil {
+		d.edited[nodes[i]] = true
+	}
+	return err
+}
+
+// AddItem adds an item to the end of the spec
+func (d *Document) AddItem(item ItemType) error {
+	items := valueOf(d.mapping(), "items")
+	switch {
+	case items == nil:
+		items = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		d.mapping().Content = append(d.mapping().Content, keyNode("items"), items)
+	case items.Kind == yaml.ScalarNode && items.Tag == "!!null":
+		// "items:" with nothing after it starts the list
+		items.Kind, items.Tag, items.Value = yaml.SequenceNode, "!!seq", ""
+	case items.Kind != yaml.SequenceNode:
+		return fmt.Errorf("items must be a list")
+	}
+
+	node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	if _, err := setItemFields(node, item); err != nil {
+		return err
+	}
+	items.Content = append(items.Content, node)
+	return nil
+}
+
+// RemoveItem removes the i-th item, with its comments
+func (d *Document) RemoveItem(i int) error {
+	items := valueOf(d.mapping(), "items")
+	if items == nil || i < 0 || i >= len(items.Content) {
+		return fmt.Errorf("no item %d", i+1)
+	}
+	items.Content = slices.Delete(items.Content, i, i+1)
+	return nil
+}
+
+// Bytes encodes the document as YAML. The lines of the source are kept but
+// for those of the items that were changed or removed, and the changed and
+// added items are encoded in their place. A spec whose items are in flow
+// style is encoded whole, which loses its blank lines.
+func (d *Document) Bytes() ([]byte, error) {
+	if d.sources == nil {
+		return encode(&d.root)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(bytes.Join(d.lines[:d.start], nil))
+	if d.newKey && len(d.itemNodes()) > 0 {
+		buf.WriteString("items:\n")
+	}
+	for i, node := range d.itemNodes() {
+		source, read := d.sources[node]
+		if read && i > 0 {
+			buf.Write(bytes.Join(d.lines[source.start:source.head], nil))
+		} else if i > 0 {
+			buf.Write(d.blank)
+		}
+		if read {
+			buf.Write(bytes.Join(d.lines[source.head:source.body], nil))
+			if !d.edited[node] {
+				buf.Write(bytes.Join(d.lines[source.body:source.end], nil))
+				continue
+			}
+		}
+		data, err := d.encodeItem(node, read)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+	buf.Write(bytes.Join(d.lines[d.end:], nil))
+	return buf.Bytes(), nil
+}
+
+// encodeItem encodes an item as an entry of the items list. The head comment
+// of an item read from the source is left out, as its lines are kept.
+func (d *Document) encodeItem(node *yaml.Node, read bool) ([]byte, error) {
+	item := *node
+	if read {
+		item.HeadComment = ""
+	}
+	data, err := encode(&yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{&item}})
+	if err != nil {
+		return nil, err
+	}
+	indent := bytes.Repeat([]byte(" "), d.indent)
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) > 0 {
+			lines[i] = append(indent[:len(indent):len(indent)], line...)
+		}
+	}
+	return bytes.Join(lines, nil), nil
+}
+
+// encode encodes a node as YAML with two space indents
+func encode(node *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// setItemFields writes the editable fields of item into its mapping node.
+// Name, style and color are always written, as a Spec would be, and so is
+// the percentage of items that use one unless it is a zero the item didn't
+// have, while the other quantities and the optional fields are removed when
+// empty. It reports whether any field changed.
+func setItemFields(mapping *yaml.Node, item ItemType) (bool, error) {
+	changed := false
+	noPercentage := item.Quantity() != QuantityPercentage || (item.Percentage == 0 && keyIndex(mapping, "percentage") < 0)
+	set := func(fieldChanged bool, err error) error {
+		changed = changed || fieldChanged
+		return err
+	}
+	for _, err := range []error{
+		set(setField(mapping, "name", item.Name, false)),
+		set(setField(mapping, "percentage", item.Percentage, noPercentage)),
+		set(setField(mapping, "count", item.Count, true)),
+		set(setField(mapping, "chance", item.Chance, true)),
+		set(setField(mapping, "min", item.Min, true)),
+		set(setField(mapping, "max", item.Max, true)),
+		set(setField(mapping, "weight", item.Weight, true)),
+		set(setField(mapping, "roll", item.Roll, true)),
+		set(setField(mapping, "style", item.Style, false)),
+		set(setField(mapping, "color", item.Color, false)),
+		set(setField(mapping, "dice", item.Dice, true)),
+		set(setField(mapping, "letter", item.Letter, true)),
+		set(setField(mapping, "size", item.Size, true)),
+	} {
+		if err != nil {
+			return changed, err
+		}
+	}
+	return changed, nil
+}
+
+// setField sets key to value in mapping. An unchanged value is left alone,
+// and a changed one keeps the comments and quoting of the value it replaces.
+// With omitEmpty, a zero value removes the key. It reports whether the
+// mapping changed.
+func setField[T any](mapping *yaml.Node, key string, value T, omitEmpty bool) (bool, error) {
+	i := keyIndex(mapping, key)
+	if omitEmpty && reflect.ValueOf(value).IsZero() {
+		if i >= 0 {
+			mapping.Content = slices.Delete(mapping.Content, i, i+2)
+		}
+		return i >= 0, nil
+	}
+
+	var old *yaml.Node
+	if i >= 0 {
+		old = mapping.Content[i+1]
+		var current T
+		if old.Decode(&current) == nil && reflect.DeepEqual(current, value) {
+			return false, nil
+		}
+	}
+
+	node := &yaml.Node{}
+	if err := node.Encode(value); err != nil {
+		return false, fmt.Errorf("failed to encode %s: %w", key, err)
+	}
+	if node.Kind == yaml.SequenceNode {
+		// As in [7, 8]
+		node.Style = yaml.FlowStyle
+	}
+	if old == nil {
+		at := itemFieldIndex(mapping, key)
+		newKey := keyNode(key)
+		if at >= 2 && at == len(mapping.Content) {
+			// A comment below the key before now goes below this one
+			before := mapping.Content[at-2]
+			newKey.FootComment, before.FootComment = before.FootComment, ""
+		}
+		mapping.Content = slices.Insert(mapping.Content, at, newKey, node)
+		return true, nil
+	}
+	if quoted := old.Style & (yaml.DoubleQuotedStyle | yaml.SingleQuotedStyle); quoted != 0 && node.Tag == "!!str" {
+		node.Style = quoted
+	}
+	node.HeadComment, node.LineComment, node.FootComment = old.HeadComment, old.LineComment, old.FootComment
+	mapping.Content[i+1] = node
+	return true, nil
+}
+
+// itemFields are the keys setItemFields writes, in order
+var itemFields = []string{"name", "percentage", "count", "chance", "min", "max", "weight", "roll", "style", "color", "dice", "letter", "size"}
+
+// itemFieldIndex returns where in an item's mapping to add key, one of
+// itemFields. The editable fields are kept together in their order, ahead of
+// placement, rules and so on.
+func itemFieldIndex(mapping *yaml.Node, key string) int {
+	at, first := -1, len(mapping.Content)
+	for _, field := range itemFields {
+		if i := keyIndex(mapping, field); i >= 0 {
+			if slices.Index(itemFields, field) < slices.Index(itemFields, key) {
+				at = max(at, i+2)
+			}
+			first = min(first, i)
+		}
+	}
+	if at < 0 {
+		// None of the fields before it are there, so it goes first
+		return first
+	}
+	return at
+}
+
+// keyIndex returns the index of key's node in a mapping, or -1
+func keyIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// valueOf returns the value of key in a mapping, or nil
+func valueOf(mapping *yaml.Node, key string) *yaml.Node {
+	if i := keyIndex(mapping, key); i >= 0 {
+		return mapping.Content[i+1]
+	}
+	return nil
+}
+
+// keyNode returns a node for a mapping key
+func keyNode(key string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+}
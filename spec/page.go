@@ -0,0 +1,167 @@
+package spec
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// PageSettings configures the pages of PDF output. Lengths are numbers of
+// millimeters, optionally followed by a unit: "mm", "cm", "in" or "pt".
+type PageSettings struct {
+	Size        string `yaml:"size,omitempty"`        // One of PageSizes (default "A4")
+	Width       string `yaml:"width,omitempty"`       // Page width for the "custom" size
+	Height      string `yaml:"height,omitempty"`      // Page height for the "custom" size
+	Orientation string `yaml:"orientation,omitempty"` // "landscape" (default) or "portrait"
+	Margin      string `yaml:"margin,omitempty"`      // Blank border around the map (default 20mm)
+	HexSize     string `yaml:"hex_size,omitempty"`    // Width of a hex across its flat sides, like "1in" (default about 14mm)
+	Fit         bool   `yaml:"fit,omitempty"`         // Scale the hexes so the whole map fits on one page
+}
+
+// PageSizes are the valid values of PageSettings.Size
+var PageSizes = []string{"A4", "A3", "Letter", "Tabloid", PageSizeCustom}
+
+// PageSizeCustom is the page size whose width and height are set explicitly
+const PageSizeCustom = "custom"
+
+// standardPageSizes are the portrait width and height of the named page sizes in mm
+var standardPageSizes = map[string][2]float64{
+	"a4":      {210, 297},
+	"a3":      {297, 420},
+	"letter":  {215.9, 279.4},
+	"tabloid": {279.4, 431.8},
+}
+
+// Orientations for PageSettings.Orientation
+const (
+	OrientationLandscape = "landscape"
+	OrientationPortrait  = "portrait"
+)
+
+// Page defaults in mm
+const (
+	DefaultPageMargin = 20.0
+	DefaultHexSize    = 8 * 1.7320508075688772 // 8mm from center to corner, as in the original fixed PDF layout
+)
+
+// PageLayout is the resolved page setup in mm
+type PageLayout struct {
+	Width, Height float64 // Page size, already turned to the orientation
+	Margin        float64
+	HexSize       float64 // Width of a hex across its flat sides
+	Fit           bool
+}
+
+// pageProblem is a problem with the PageSettings field key
+type pageProblem struct {
+	key     string
+	message string
+}
+
+// Layout resolves the settings to a page layout, filling in the defaults
+func (p PageSettings) Layout() (PageLayout, error) {
+	layout, problems := p.resolve()
+	if len(problems) > 0 {
+		return PageLayout{}, fmt.Errorf("%s", problems[0].message)
+	}
+	return layout, nil
+}
+
+// resolve works out the layout and reports every problem with the settings
+func (p PageSettings) resolve() (PageLayout, []pageProblem) {
+	var problems []pageProblem
+	layout := PageLayout{
+		Margin:  DefaultPageMargin,
+		HexSize: DefaultHexSize,
+		Fit:     p.Fit,
+	}
+
+	length := func(key, value string, fallback float64) float64 {
+		if value == "" {
+			return fallback
+		}
+		mm, err := ParseLength(value)
+		if err == nil && mm <= 0 && key != "margin" {
+			err = fmt.Errorf("%q must be greater than zero", value)
+		}
+		if err != nil {
+			problems = append(problems, pageProblem{key, fmt.Sprintf("invalid page %s: %v", strings.ReplaceAll(key, "_", " "), err)})
+			return fallback
+		}
+		return mm
+	}
+
+	size := p.Size
+	if size == "" {
+		size = "A4"
+	}
+	if strings.EqualFold(size, PageSizeCustom) {
+		if p.Width == "" || p.Height == "" {
+			problems = append(problems, pageProblem{"size", "custom page size requires width and height"})
+		}
+		layout.Width = length("width", p.Width, 0)
+		layout.Height = length("height", p.Height, 0)
+	} else if dimensions, ok := standardPageSizes[strings.ToLower(size)]; ok {
+		layout.Width, layout.Height = dimensions[0], dimensions[1]
+		if p.Width != "" || p.Height != "" {
+			problems = append(problems, pageProblem{"size", fmt.Sprintf("page width and height need size %s", PageSizeCustom)})
+		}
+	} else {
+		problems = append(problems, pageProblem{"size", fmt.Sprintf("invalid page size: %s (must be one of %s)", p.Size, strings.Join(PageSizes, ", "))})
+	}
+
+	switch p.Orientation {
+	case "", OrientationLandscape:
+		if layout.Width < layout.Height {
+			layout.Width, layout.Height = layout.Height, layout.Width
+		}
+	case OrientationPortrait:
+		if layout.Width > layout.Height {
+			layout.Width, layout.Height = layout.Height, layout.Width
+		}
+	default:
+		problems = append(problems, pageProblem{"orientation", fmt.Sprintf("invalid page orientation: %s (must be '%s' or '%s')", p.Orientation, OrientationLandscape, OrientationPortrait)})
+	}
+
+	layout.Margin = length("margin", p.Margin, layout.Margin)
+	layout.HexSize = length("hex_size", p.HexSize, layout.HexSize)
+
+	if len(problems) == 0 && (2*layout.Margin >= layout.Width || 2*layout.Margin >= layout.Height) {
+		problems = append(problems, pageProblem{"margin", fmt.Sprintf("page margin %gmm leaves no room on a %gx%gmm page", layout.Margin, layout.Width, layout.Height)})
+	}
+
+	return layout, problems
+}
+
+// lengthUnits are the millimeters in each unit ParseLength accepts
+var lengthUnits = []struct {
+	suffix string
+	mm     float64
+}{
+	{"mm", 1},
+	{"cm", 10},
+	{"in", 25.4},
+	{"pt", 25.4 / 72},
+}
+
+// ParseLength parses a length such as "25mm", "2.5cm", "1in" or "72pt" and
+// returns it in millimeters. A number without a unit is in millimeters.
+func ParseLength(s string) (float64, error) {
+	value, scale := strings.TrimSpace(s), 1.0
+	for _, unit := range lengthUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value, scale = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix)), unit.mm
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsInf(number, 0) || math.IsNaN(number) {
+		return 0, fmt.Errorf("%q is not a length (like 25mm, 2.5cm, 1in or 72pt)", s)
+	}
+	if number < 0 {
+		return 0, fmt.Errorf("%q must not be negative", s)
+	}
+	return number * scale, nil
+}
@@ -0,0 +1,356 @@
+// Package spec defines the YAML grid spec format and loads and validates spec files.
+package spec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ItemType represents a type of item that can be placed in the hex grid
+type ItemType struct {
+	Name       string  `yaml:"name"`
+	Percentage float64 `yaml:"percentage"`
+	Style      string  `yaml:"style"` // "dot" or "fill"
+	Color      string  `yaml:"color"`
+	Dice       string  `yaml:"dice,omitempty"`   // Optional dice expression like "2d6", "4d6kh3" or "d66"
+	Letter     string  `yaml:"letter,omitempty"` // Optional letter like "F", "G", "K", "M", "N", etc.
+	Size       string  `yaml:"size,omitempty"`   // Optional dot size; one of Sizes
+
+	// By default an item covers Percentage of the grid. Count instead gives
+	// the number of cells, either exactly or as a dice expression like "1d3"
+	// rolled for every grid, and Chance gives every cell left after the
+	// other items a percent chance of holding the item. Min and Max bound
+	// the number of cells the item gets (a Max of 0 is no limit); set on
+	// their own, the number is picked at random between them.
+	Count  string  `yaml:"count,omitempty"`
+	Chance float64 `yaml:"chance,omitempty"`
+	Min    int     `yaml:"min,omitempty"`
+	Max    int     `yaml:"max,omitempty"`
+
+	// Weight and Roll pick the item of a hex for the occurrence generator.
+	// Without an occurrence table, items are picked at random in proportion
+	// to their Weight; with one, the item whose Roll contains the table roll
+	// is picked.
+	Weight float64   `yaml:"weight,omitempty"`
+	Roll   RollRange `yaml:"roll,omitempty"`
+
+	// Placement controls how the item's cells are spread over the grid:
+	// "scatter" (the default) places them independently at random, while
+	// "clustered" grows contiguous regions of up to ClusterSize cells
+	// (0 means unlimited). Cohesion from 0 to 1 makes the regions more compact.
+	Placement   string  `yaml:"placement,omitempty"`
+	ClusterSize int     `yaml:"cluster_size,omitempty"`
+	Cohesion    float64 `yaml:"cohesion,omitempty"`
+
+	// Elevation and Moisture are the [min, max] bands, from 0 to 1, that
+	// the noise generator maps to this item. A missing band matches any value.
+	Elevation Band `yaml:"elevation,omitempty"`
+	Moisture  Band `yaml:"moisture,omitempty"`
+
+	Rules []Rule `yaml:"rules,omitempty"` // Optional constraints on where the item may be placed
+}
+
+// Quantity modes returned by ItemType.Quantity
+const (
+	QuantityPercentage = "percentage"
+	QuantityCount      = "count"
+	QuantityChance     = "chance"
+	QuantityRange      = "range"
+	QuantityWeight     = "weight" // picked by the occurrence generator
+	QuantityRoll       = "roll"   // picked by the occurrence generator
+)
+
+// Quantities are the quantity modes an item can have
+var Quantities = []string{QuantityPercentage, QuantityCount, QuantityChance, QuantityRange, QuantityWeight, QuantityRoll}
+
+// Quantity returns how the number of cells the item gets is decided: by
+// Count, Chance, Roll, Weight, a Min to Max range alone, or else by Percentage
+func (item ItemType) Quantity() string {
+	switch {
+	case item.Count != "":
+		return QuantityCount
+	case item.Chance > 0:
+		return QuantityChance
+	case item.Roll != nil:
+		return QuantityRoll
+	case item.Weight > 0:
+		return QuantityWeight
+	case item.Percentage == 0 && (item.Min > 0 || item.Max > 0):
+		return QuantityRange
+	}
+	return QuantityPercentage
+}
+
+// QuantityString describes how much of the grid the item covers, e.g.
+// "12.5%", "1d3 hexes", "5.0% per hex", "1-3 hexes", "weight 2" or "roll 7-8"
+func (item ItemType) QuantityString() string {
+	var text string
+	switch item.Quantity() {
+	case QuantityCount:
+		text = item.Count + " hexes"
+		if item.Count == "1" {
+			text = "1 hex"
+		}
+	case QuantityChance:
+		text = fmt.Sprintf("%.1f%% per hex", item.Chance)
+	case QuantityWeight:
+		return fmt.Sprintf("weight %g", item.Weight)
+	case QuantityRoll:
+		return "roll " + item.Roll.String()
+	case QuantityRange:
+		if item.Max == 0 {
+			return fmt.Sprintf("at least %d hexes", item.Min)
+		}
+		return fmt.Sprintf("%d-%d hexes", item.Min, item.Max)
+	default:
+		text = fmt.Sprintf("%.1f%%", item.Percentage)
+	}
+
+	switch {
+	case item.Min > 0 && item.Max > 0:
+		text += fmt.Sprintf(", %d-%d hexes", item.Min, item.Max)
+	case item.Min > 0:
+		text += fmt.Sprintf(", at least %d", item.Min)
+	case item.Max > 0:
+		text += fmt.Sprintf(", at most %d", item.Max)
+	}
+	return text
+}
+
+// Rule constrains where an item may be placed relative to other items
+type Rule struct {
+	Type     string   `yaml:"type"`               // "adjacent", "exclude" or "surrounded"
+	Items    []string `yaml:"items"`              // Names of the other item types
+	Distance int      `yaml:"distance,omitempty"` // Range in hexes for "exclude" rules (default 1)
+}
+
+// Rule types for Rule.Type
+const (
+	RuleAdjacent   = "adjacent"   // at least one neighbor must be one of Items
+	RuleExclude    = "exclude"    // no other cell within Distance may be one of Items
+	RuleSurrounded = "surrounded" // every neighbor must be one of Items
+)
+
+// Range returns how far from its cell the rule looks
+func (r Rule) Range() int {
+	if r.Type == RuleExclude && r.Distance > 1 {
+		return r.Distance
+	}
+	return 1
+}
+
+// String describes the rule, e.g. "not within 2 hexes of Black Hole"
+func (r Rule) String() string {
+	items := strings.Join(r.Items, " or ")
+	switch r.Type {
+	case RuleAdjacent:
+		return "must be adjacent to " + items
+	case RuleExclude:
+		return fmt.Sprintf("must not be within %d hexes of %s", r.Range(), items)
+	case RuleSurrounded:
+		return "must be surrounded by " + items
+	}
+	return r.Type + " " + items
+}
+
+// RollRange is an inclusive [min, max] range of dice totals
+type RollRange []int
+
+// Contains reports whether total lies in the range. A nil range contains
+// no totals.
+func (r RollRange) Contains(total int) bool {
+	return len(r) == 2 && total >= r[0] && total <= r[1]
+}
+
+// String formats the range as "7-8", or "7" if it holds a single total
+func (r RollRange) String() string {
+	if len(r) != 2 {
+		return fmt.Sprint([]int(r))
+	}
+	if r[0] == r[1] {
+		return fmt.Sprint(r[0])
+	}
+	return fmt.Sprintf("%d-%d", r[0], r[1])
+}
+
+// Band is a [min, max] range of normalized noise values
+type Band []float64
+
+// Contains reports whether value lies in the band. The band includes its
+// minimum and excludes its maximum, except that a maximum of 1 is included.
+// A nil band contains every value.
+func (b Band) Contains(value float64) bool {
+	if b == nil {
+		return true
+	}
+	return value >= b[0] && (value < b[1] || b[1] >= 1)
+}
+
+// Sizes are the valid values of ItemType.Size, from smallest to largest
+var Sizes = []string{"small", "medium", "large", "x-large", "xx-large"}
+
+// MaxTotalPercentage is the most the percentages of a spec's items may add up to
+const MaxTotalPercentage = 100
+
+// Placement modes for ItemType.Placement
+const (
+	PlacementScatter   = "scatter"
+	PlacementClustered = "clustered"
+)
+
+// Spec represents the YAML configuration file structure
+type Spec struct {
+	Default    string             `yaml:"default"`
+	Seed       int64              `yaml:"seed,omitempty"`       // Optional random seed for reproducible grids (0 means random)
+	Generator  string             `yaml:"generator,omitempty"`  // Optional "shuffle" (default), "noise" or "occurrence"
+	Noise      NoiseSettings      `yaml:"noise,omitempty"`      // Settings for the noise generator
+	Occurrence OccurrenceSettings `yaml:"occurrence,omitempty"` // Settings for the occurrence generator
+	Layout     HexLayout          `yaml:"layout,omitempty"`     // Shape and arrangement of the hexes
+	Labels     LabelSettings      `yaml:"labels,omitempty"`     // Optional coordinate labels on every hex
+	Page       PageSettings       `yaml:"page,omitempty"`       // Page setup for PDF output
+	Items      []ItemType         `yaml:"items"`
+}
+
+// NoiseSettings configures the elevation and moisture fields of the noise generator
+type NoiseSettings struct {
+	Scale   float64 `yaml:"scale,omitempty"`   // Noise frequency per hex; smaller values give larger features (default 0.15)
+	Octaves int     `yaml:"octaves,omitempty"` // Number of layers of finer detail (default 4)
+}
+
+// OccurrenceSettings configures the occurrence generator, which rolls for
+// every hex whether it holds an item, and if so rolls or picks which one
+type OccurrenceSettings struct {
+	Dice   string `yaml:"dice,omitempty"`   // Presence roll made for every hex, e.g. "1d6"
+	Target int    `yaml:"target,omitempty"` // Least total of Dice that puts an item in the hex, e.g. 4 for "4+"
+	Table  string `yaml:"table,omitempty"`  // Optional dice rolled to pick the item by its Roll; without it items are picked by Weight
+}
+
+// HexLayout chooses the shape of the hexes and how the rows or columns of
+// the grid are shifted against each other
+type HexLayout struct {
+	Orientation string `yaml:"orientation,omitempty"` // "flat" (default) or "pointy" topped hexes
+	Offset      string `yaml:"offset,omitempty"`      // One of the offsets for the orientation; see Resolve
+}
+
+// Hex orientations for HexLayout.Orientation
+const (
+	OrientationFlat   = "flat"
+	OrientationPointy = "pointy"
+)
+
+// Offsets for HexLayout.Offset. Flat-top hexes use OffsetStagger, OffsetOddQ
+// or OffsetEvenQ, and pointy-top hexes use OffsetOddR or OffsetEvenR.
+const (
+	OffsetStagger = "stagger" // rows half a hex apart, every other row shifted right by half a column
+	OffsetOddQ    = "odd-q"   // odd columns shifted down by half a hex
+	OffsetEvenQ   = "even-q"  // even columns shifted down by half a hex
+	OffsetOddR    = "odd-r"   // odd rows shifted right by half a hex
+	OffsetEvenR   = "even-r"  // even rows shifted right by half a hex
+)
+
+// Resolve returns the orientation and offset with their defaults filled in:
+// flat-top hexes default to OffsetStagger and pointy-top hexes to OffsetOddR
+func (l HexLayout) Resolve() (orientation, offset string) {
+	orientation, offset = l.Orientation, l.Offset
+	if orientation == "" {
+		orientation = OrientationFlat
+	}
+	if offset == "" {
+		offset = OffsetStagger
+		if orientation == OrientationPointy {
+			offset = OffsetOddR
+		}
+	}
+	return orientation, offset
+}
+
+// offsetsFor lists the offsets that can be used with each orientation
+var offsetsFor = map[string][]string{
+	OrientationFlat:   {OffsetStagger, OffsetOddQ, OffsetEvenQ},
+	OrientationPointy: {OffsetOddR, OffsetEvenR},
+}
+
+// LabelSettings configures the coordinate label drawn on every hex
+type LabelSettings struct {
+	Format   string  `yaml:"format,omitempty"`    // One of LabelFormats; empty draws no labels
+	FontSize float64 `yaml:"font_size,omitempty"` // Text height relative to the hex size, the distance from its center to a corner (default 0.3)
+	Position string  `yaml:"position,omitempty"`  // "top" (default), "center" or "bottom" of the hex
+}
+
+// Label formats for LabelSettings.Format
+const (
+	LabelXXYY  = "xxyy"  // column then row, two digits each and counting from 01: "0101", "0102"
+	LabelA1    = "a1"    // column letter then row number: "A1", "A2"
+	LabelAxial = "axial" // axial coordinate: "0,0", "1,-1"
+)
+
+// LabelFormats are the valid values of LabelSettings.Format
+var LabelFormats = []string{LabelXXYY, LabelA1, LabelAxial}
+
+// Label positions for LabelSettings.Position
+const (
+	LabelTop    = "top"
+	LabelCenter = "center"
+	LabelBottom = "bottom"
+)
+
+// DefaultLabelFontSize is the label text height when LabelSettings.FontSize is 0
+const DefaultLabelFontSize = 0.3
+
+// Generators for Spec.Generator
+const (
+	GeneratorShuffle    = "shuffle"
+	GeneratorNoise      = "noise"
+	GeneratorOccurrence = "occurrence"
+)
+
+// Load loads and parses the YAML configuration file
+func Load(filePath string) (*Spec, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read YAML file: %w", err)
+	}
+
+	return Parse(data)
+}
+
+// Parse parses and validates YAML configuration data. If the spec has
+// problems, the error is a *ValidationError listing all of them.
+func Parse(data []byte) (*Spec, error) {
+	// The node tree gives the line and column of every value
+	var root yaml.Node
+	err := yaml.Unmarshal(data, &root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+
+	v := &validator{}
+	var config Spec
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err = decoder.Decode(&config)
+	var typeErr *yaml.TypeError
+	switch {
+	case errors.As(err, &typeErr):
+		// Fields of the wrong type or with unknown names; the rest of the
+		// spec is still decoded, so carry on validating it
+		for _, msg := range typeErr.Errors {
+			v.addTypeError(msg)
+		}
+	case err != nil && err != io.EOF: // io.EOF means an empty file
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+
+	v.validate(&config, &root)
+	if len(v.problems) > 0 {
+		return nil, v.err()
+	}
+
+	return &config, nil
+}
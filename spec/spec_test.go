@@ -0,0 +1,485 @@
+package spec
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	// Create a temporary YAML file for testing
+	testYAML := `default: "#FFFFFF"
+items:
+  - name: "Test Item"
+    percentage: 50.0
+    style: "fill"
+    color: "#FF0000"
+  - name: "Test Dot"
+    percentage: 50.0
+    style: "dot"
+    color: "#00FF00"`
+
+	tmpFile, err := os.CreateTemp("", "test_config_*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.WriteString(testYAML)
+	if err != nil {
+		t.Fatalf("Failed to write test YAML: %v", err)
+	}
+	tmpFile.Close()
+
+	// Test loading the configuration
+	config, err := Load(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("Failed to load YAML config: %v", err)
+	}
+
+	// Verify the configuration
+	if len(config.Items) != 2 {
+		t.Errorf("Expected 2 items, got %d", len(config.Items))
+	}
+
+	if config.Items[0].Name != "Test Item" {
+		t.Errorf("Expected first item name to be 'Test Item', got '%s'", config.Items[0].Name)
+	}
+
+	if config.Items[0].Percentage != 50.0 {
+		t.Errorf("Expected first item percentage to be 50.0, got %f", config.Items[0].Percentage)
+	}
+
+	if config.Items[0].Style != "fill" {
+		t.Errorf("Expected first item style to be 'fill', got '%s'", config.Items[0].Style)
+	}
+
+	if config.Items[1].Style != "dot" {
+		t.Errorf("Expected second item style to be 'dot', got '%s'", config.Items[1].Style)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		msg  string
+	}{
+		{"bad dice", `default: "#FFFFFF"
+items:
+  - {name: "Treasure", percentage: 10, style: "dot", color: "#FFD700", dice: "2d6+"}`, "invalid dice for item Treasure"},
+		{"bad placement", `default: "#FFFFFF"
+items:
+  - {name: "Forest", percentage: 10, style: "fill", color: "#228B22", placement: "piles"}`, "invalid placement"},
+		{"bad band", `default: "#FFFFFF"
+generator: "noise"
+items:
+  - {name: "Water", style: "fill", color: "#4169E1", elevation: [0.5, 0.2]}`, "invalid elevation band"},
+		{"noise without bands", `default: "#FFFFFF"
+generator: "noise"
+items:
+  - {name: "Water", style: "fill", color: "#4169E1"}`, "requires items with elevation or moisture bands"},
+		{"unknown rule item", `default: "#FFFFFF"
+items:
+  - name: "Village"
+    percentage: 10
+    style: "dot"
+    color: "#FFD700"
+    rules:
+      - {type: "adjacent", items: ["Lake"]}`, "unknown item: Lake"},
+		{"bad rule type", `default: "#FFFFFF"
+items:
+  - name: "Village"
+    percentage: 10
+    style: "dot"
+    color: "#FFD700"
+    rules:
+      - {type: "near", items: ["Village"]}`, "invalid rule type"},
+		{"bad orientation", `default: "#FFFFFF"
+layout: {orientation: "round"}
+items:
+  - {name: "Forest", percentage: 10, style: "fill", color: "#228B22"}`, "invalid hex orientation: round"},
+		{"offset for other orientation", `default: "#FFFFFF"
+layout: {orientation: "pointy", offset: "odd-q"}
+items:
+  - {name: "Forest", percentage: 10, style: "fill", color: "#228B22"}`, "invalid offset for pointy hexes: odd-q (must be one of odd-r, even-r)"},
+		{"bad label format", `default: "#FFFFFF"
+labels: {format: "roman"}
+items:
+  - {name: "Forest", percentage: 10, style: "fill", color: "#228B22"}`, "invalid label format: roman"},
+		{"bad label position", `default: "#FFFFFF"
+labels: {format: "xxyy", position: "left", font_size: 2}
+items:
+  - {name: "Forest", percentage: 10, style: "fill", color: "#228B22"}`, "invalid label position: left"},
+		{"bad page size", `default: "#FFFFFF"
+page: {size: "B5"}
+items:
+  - {name: "Forest", percentage: 10, style: "fill", color: "#228B22"}`, "invalid page size: B5"},
+		{"custom page without height", `default: "#FFFFFF"
+page: {size: "custom", width: "30in"}
+items:
+  - {name: "Forest", percentage: 10, style: "fill", color: "#228B22"}`, "requires width and height"},
+		{"bad hex size", `default: "#FFFFFF"
+page: {hex_size: "1 furlong"}
+items:
+  - {name: "Forest", percentage: 10, style: "fill", color: "#228B22"}`, "invalid page hex size"},
+		{"margin too large", `default: "#FFFFFF"
+page: {size: "A4", margin: "5in"}
+items:
+  - {name: "Forest", percentage: 10, style: "fill", color: "#228B22"}`, "leaves no room"},
+		{"bad count", `default: "#FFFFFF"
+items:
+  - {name: "Castle", count: "1d", style: "dot", color: "#FFD700"}`, "invalid count for item Castle"},
+		{"negative count", `default: "#FFFFFF"
+items:
+  - {name: "Castle", count: "1d3-4", style: "dot", color: "#FFD700"}`, "can be negative"},
+		{"two quantities", `default: "#FFFFFF"
+items:
+  - {name: "Castle", percentage: 5, count: 2, style: "dot", color: "#FFD700"}`, "sets both percentage and count"},
+		{"max below min", `default: "#FFFFFF"
+items:
+  - {name: "Castle", min: 3, max: 2, style: "dot", color: "#FFD700"}`, "invalid max for item Castle"},
+		{"total chance", `default: "#FFFFFF"
+items:
+  - {name: "Star", chance: 60, style: "dot", color: "#FFD700"}
+  - {name: "Nebula", chance: 50, style: "fill", color: "#800080"}`, "total chance exceeds 100%"},
+		{"occurrence without dice", `default: "#FFFFFF"
+generator: "occurrence"
+items:
+  - {name: "Star", weight: 1, style: "dot", color: "#FFD700"}`, "occurrence generator requires occurrence dice"},
+		{"unreachable target", `default: "#FFFFFF"
+generator: "occurrence"
+occurrence: {dice: "1d6", target: 7}
+items:
+  - {name: "Star", weight: 1, style: "dot", color: "#FFD700"}`, "invalid occurrence target: 7 (1d6 rolls at most 6)"},
+		{"percentage with occurrence", `default: "#FFFFFF"
+generator: "occurrence"
+occurrence: {dice: "1d6", target: 4}
+items:
+  - {name: "Star", percentage: 10, weight: 1, style: "dot", color: "#FFD700"}`, "percentage of item Star is not used by the occurrence generator"},
+		{"roll without table", `default: "#FFFFFF"
+generator: "occurrence"
+occurrence: {dice: "1d6", target: 4}
+items:
+  - {name: "Star", roll: [2, 7], style: "dot", color: "#FFD700"}`, "roll of item Star needs an occurrence table"},
+		{"roll outside table", `default: "#FFFFFF"
+generator: "occurrence"
+occurrence: {dice: "1d6", target: 4, table: "2d6"}
+items:
+  - {name: "Star", roll: [10, 13], style: "dot", color: "#FFD700"}`, "roll 10-13 of item Star is outside the table (2d6 rolls 2 to 12)"},
+		{"overlapping rolls", `default: "#FFFFFF"
+generator: "occurrence"
+occurrence: {dice: "1d6", target: 4, table: "2d6"}
+items:
+  - {name: "Star", roll: [2, 7], style: "dot", color: "#FFD700"}
+  - {name: "Giant", roll: [7, 12], style: "dot", color: "#FF0000"}`, "roll 7-12 of item Giant overlaps item Star"},
+		{"weight without occurrence", `default: "#FFFFFF"
+items:
+  - {name: "Star", weight: 2, style: "dot", color: "#FFD700"}`, "only used by the occurrence generator"},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.yaml))
+		if err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.msg, err)
+		}
+	}
+}
+
+func TestParseReportsAllProblems(t *testing.T) {
+	yamlData := `default: "#FFFFFF"
+items:
+  - name: "Forest"
+    percentage: 60
+    style: "fill"
+    color: "green"
+  - name: "Star"
+    percentage: 50
+    style: "dot"
+    color: "#FFD700"
+    dice: "2d"
+    letter: "TOOLONG"
+    size: "huge"
+    colour: "#000000"
+`
+
+	_, err := Parse([]byte(yamlData))
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a ValidationError, got %v", err)
+	}
+
+	expected := []Problem{
+		{Line: 3, Column: 3, Message: "total percentage exceeds 100%: 110"},
+		{Line: 6, Column: 12, Message: `invalid color for item Forest: "green" must start with '#'`},
+		{Line: 11, Column: 11, Message: "invalid dice for item Star"},
+		{Line: 12, Column: 13, Message: "invalid letter for item Star"},
+		{Line: 13, Column: 11, Message: "invalid size for item Star: huge"},
+		{Line: 14, Message: "field colour not found"},
+	}
+	if len(validationErr.Problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %d:\n%v", len(expected), len(validationErr.Problems), err)
+	}
+	for i, want := range expected {
+		got := validationErr.Problems[i]
+		if got.Line != want.Line || got.Column != want.Column || !strings.Contains(got.Message, want.Message) {
+			t.Errorf("Problem %d: expected %v, got %v", i, want, got)
+		}
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		color   string
+		r, g, b int
+		valid   bool
+	}{
+		{"#FF8000", 255, 128, 0, true},
+		{"#f80", 255, 136, 0, true},
+		{"#000000", 0, 0, 0, true},
+		{"FF8000", 0, 0, 0, false},
+		{"#FF80", 0, 0, 0, false},
+		{"#GGGGGG", 0, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		r, g, b, err := ParseColor(tt.color)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid=%v, got error %v", tt.color, tt.valid, err)
+		}
+		if tt.valid && (r != tt.r || g != tt.g || b != tt.b) {
+			t.Errorf("%s: expected %d,%d,%d, got %d,%d,%d", tt.color, tt.r, tt.g, tt.b, r, g, b)
+		}
+	}
+}
+
+func TestPageLayout(t *testing.T) {
+	tests := []struct {
+		page          PageSettings
+		width, height float64
+		margin, hexMM float64
+	}{
+		{PageSettings{}, 297, 210, DefaultPageMargin, DefaultHexSize},
+		{PageSettings{Size: "letter", Orientation: "portrait", HexSize: "1in"}, 215.9, 279.4, DefaultPageMargin, 25.4},
+		{PageSettings{Size: "A3", Margin: "1cm"}, 420, 297, 10, DefaultHexSize},
+		{PageSettings{Size: "custom", Width: "24in", Height: "36in", Margin: "0"}, 914.4, 609.6, 0, DefaultHexSize},
+	}
+	for _, tt := range tests {
+		layout, err := tt.page.Layout()
+		if err != nil {
+			t.Errorf("%+v: %v", tt.page, err)
+			continue
+		}
+		if math.Abs(layout.Width-tt.width) > 1e-9 || math.Abs(layout.Height-tt.height) > 1e-9 ||
+			layout.Margin != tt.margin || math.Abs(layout.HexSize-tt.hexMM) > 1e-9 {
+			t.Errorf("%+v: got %+v", tt.page, layout)
+		}
+	}
+
+	for _, length := range []string{"", "mm", "-3mm", "2yd", "1e400"} {
+		if _, err := ParseLength(length); err == nil {
+			t.Errorf("Expected length %q to be rejected", length)
+		}
+	}
+	if mm, err := ParseLength("72pt"); err != nil || math.Abs(mm-25.4) > 1e-9 {
+		t.Errorf("Expected 72pt to be 25.4mm, got %g (%v)", mm, err)
+	}
+}
+
+func TestGridSpecsAreValid(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "grid-specs", "*.yaml"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("Failed to find grid specs: %v", err)
+	}
+	for _, file := range files {
+		if _, err := Load(file); err != nil {
+			t.Errorf("%s: %v", file, err)
+		}
+	}
+}
+
+func TestQuantity(t *testing.T) {
+	for _, tt := range []struct {
+		item     ItemType
+		quantity string
+		text     string
+	}{
+		{ItemType{Percentage: 12.5}, QuantityPercentage, "12.5%"},
+		{ItemType{Percentage: 0.147, Min: 1}, QuantityPercentage, "0.1%, at least 1"},
+		{ItemType{Count: "1d3"}, QuantityCount, "1d3 hexes"},
+		{ItemType{Count: "1"}, QuantityCount, "1 hex"},
+		{ItemType{Chance: 5, Max: 10}, QuantityChance, "5.0% per hex, at most 10"},
+		{ItemType{Min: 1, Max: 3}, QuantityRange, "1-3 hexes"},
+		{ItemType{Min: 2}, QuantityRange, "at least 2 hexes"},
+		{ItemType{Weight: 2.5}, QuantityWeight, "weight 2.5"},
+		{ItemType{Roll: RollRange{7, 8}}, QuantityRoll, "roll 7-8"},
+		{ItemType{Roll: RollRange{2, 2}}, QuantityRoll, "roll 2"},
+	} {
+		if quantity := tt.item.Quantity(); quantity != tt.quantity {
+			t.Errorf("%+v: expected quantity %s, got %s", tt.item, tt.quantity, quantity)
+		}
+		if text := tt.item.QuantityString(); text != tt.text {
+			t.Errorf("%+v: expected %q, got %q", tt.item, tt.text, text)
+		}
+	}
+}
+
+func TestDocument(t *testing.T) {
+	doc, err := ParseDocument([]byte(`# Fantasy map
+default: "#F5F5DC" # parchment
+items:
+  # Most of the map
+  - name: "Forest"
+    percentage: 35.0 # tweak me
+    style: "fill"
+    color: "#228B22"
+    placement: clustered
+
+  - name: "Village"
+    percentage: 10.0
+    style: "dot"
+    color: "#CD853F"
+    dice: "2d6"
+
+labels:
+  format: "xxyy"
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+	items, err := doc.Items()
+	if err != nil || len(items) != 2 || items[0].Placement != PlacementClustered {
+		t.Fatalf("Expected two items, the first clustered, got %+v, %v", items, err)
+	}
+
+	forest := items[0]
+	forest.Percentage, forest.Letter = 40, "F"
+	village := items[1]
+	village.Dice = ""
+	for _, err := range []error{
+		doc.SetItem(0, forest),
+		doc.SetItem(1, village),
+		doc.AddItem(ItemType{Name: "Sea", Percentage: 20, Style: "fill", Color: "#0000FF"}),
+		doc.RemoveItem(1),
+	} {
+		if err != nil {
+			t.Fatalf("Failed to edit document: %v", err)
+		}
+	}
+	if err := doc.SetItem(2, forest); err == nil {
+		t.Error("Expected setting a missing item to fail")
+	}
+
+	data, err := doc.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to encode document: %v", err)
+	}
+	out := string(data)
+	for _, want := range []string{
+		"# Fantasy map\n",
+		`default: "#F5F5DC" # parchment`,
+		"  # Most of the map\n  - name: \"Forest\"\n",
+		"percentage: 40 # tweak me\n",
+		"letter: F\n    placement: clustered\n\n  - name: Sea\n",
+		"color: '#0000FF'\n\nlabels:\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected the document to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Village") {
+		t.Errorf("Expected the village to be removed, got:\n%s", out)
+	}
+
+	config, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Expected the edited spec to be valid: %v", err)
+	}
+	if len(config.Items) != 2 || config.Items[1].Name != "Sea" || config.Items[1].Color != "#0000FF" {
+		t.Errorf("Expected forest and sea, got %+v", config.Items)
+	}
+}
+
+func TestDocumentKeepsUnchangedLines(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "grid-specs", "*.yaml"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("Failed to find grid specs: %v", err)
+	}
+	for _, file := range files {
+		original, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		doc, err := ParseDocument(original)
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		items, err := doc.Items()
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		for i, item := range items {
+			if err := doc.SetItem(i, item); err != nil {
+				t.Fatalf("%s: %v", file, err)
+			}
+		}
+		if data, err := doc.Bytes(); err != nil || string(data) != string(original) {
+			t.Errorf("%s: expected setting the items as they were to change nothing, got (%v):\n%s", file, err, data)
+		}
+	}
+}
+
+func TestDocumentWithoutItems(t *testing.T) {
+	sea := ItemType{Name: "Sea", Percentage: 20, Style: "fill", Color: "#0000FF"}
+	tests := []struct {
+		name, source, want string
+	}{
+		{
+			"empty items",
+			"# Map to be\ndefault: \"#FFFFFF\"\n\nitems: # none yet\n\nseed: 7\n",
+			"# Map to be\ndefault: \"#FFFFFF\"\n\nitems: # none yet\n  - name: Sea\n    percentage: 20\n    style: fill\n    color: '#0000FF'\n\nseed: 7\n",
+		},
+		{
+			"only comments",
+			"# A map of the coast\n\n# to be filled in\n",
+			"# A map of the coast\n\n# to be filled in\nitems:\n  - name: Sea\n    percentage: 20\n    style: fill\n    color: '#0000FF'\n",
+		},
+		{
+			"no items key",
+			"default: \"#FFFFFF\" # white\n",
+			"default: \"#FFFFFF\" # white\nitems:\n  - name: Sea\n    percentage: 20\n    style: fill\n    color: '#0000FF'\n",
+		},
+	}
+	for _, tt := range tests {
+		doc, err := ParseDocument([]byte(tt.source))
+		if err != nil {
+			t.Fatalf("%s: failed to parse document: %v", tt.name, err)
+		}
+
+		// Nothing is lost before anything is added
+		if data, err := doc.Bytes(); err != nil || string(data) != tt.source {
+			t.Errorf("%s: expected the source back unchanged, got (%v):\n%s", tt.name, err, data)
+		}
+
+		if err := doc.AddItem(sea); err != nil {
+			t.Fatalf("%s: failed to add an item: %v", tt.name, err)
+		}
+		data, err := doc.Bytes()
+		if err != nil {
+			t.Fatalf("%s: failed to encode document: %v", tt.name, err)
+		}
+		if string(data) != tt.want {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", tt.name, tt.want, data)
+		}
+		reread, err := ParseDocument(data)
+		if err != nil {
+			t.Fatalf("%s: failed to parse the result: %v", tt.name, err)
+		}
+		if items, err := reread.Items(); err != nil || len(items) != 1 || items[0].Name != "Sea" {
+			t.Errorf("%s: expected the sea to be read back, got %+v, %v", tt.name, items, err)
+		}
+	}
+}
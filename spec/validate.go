@@ -0,0 +1,416 @@
+package spec
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+
+	"hexgrid/dice"
+)
+
+// maxLetterLength is the longest ItemType.Letter that fits in a hex
+const maxLetterLength = 3
+
+// Problem is a single problem found in a spec, at a line and column of the YAML file
+type Problem struct {
+	Line    int
+	Column  int // 0 if only the line is known
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Column == 0 {
+		return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+	}
+	return fmt.Sprintf("line %d, column %d: %s", p.Line, p.Column, p.Message)
+}
+
+// ValidationError reports every problem found in a spec
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return e.Problems[0].String()
+	}
+
+	lines := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		lines[i] = "\t" + problem.String()
+	}
+	return fmt.Sprintf("%d problems:\n%s", len(e.Problems), strings.Join(lines, "\n"))
+}
+
+// validator collects the problems in a spec
+type validator struct {
+	problems []Problem
+}
+
+// add records a problem at the position of node
+func (v *validator) add(node *yaml.Node, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// addTypeError records a decoding error from yaml.TypeError, which has the
+// form "line N: message"
+func (v *validator) addTypeError(msg string) {
+	var problem Problem
+	if n, _ := fmt.Sscanf(msg, "line %d:", &problem.Line); n == 1 {
+		msg = strings.TrimSpace(msg[strings.Index(msg, ":")+1:])
+	}
+	problem.Message = msg
+	v.problems = append(v.problems, problem)
+}
+
+// err returns the problems, in file order, as a *ValidationError
+func (v *validator) err() *ValidationError {
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		a, b := v.problems[i], v.problems[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return &ValidationError{Problems: v.problems}
+}
+
+// field returns the value node for key in a mapping node, or the mapping
+// node itself if the key is missing, so problems always have a position
+func field(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(mapping.Content); i += 2 {
+			if mapping.Content[i].Value == key {
+				return mapping.Content[i+1]
+			}
+		}
+	}
+	return mapping
+}
+
+// element returns the i-th element of a sequence node, or the node itself
+// if there is no such element
+func element(sequence *yaml.Node, i int) *yaml.Node {
+	if sequence.Kind == yaml.SequenceNode && i < len(sequence.Content) {
+		return sequence.Content[i]
+	}
+	return sequence
+}
+
+// validate checks the decoded config, using root to locate the problems
+func (v *validator) validate(config *Spec, root *yaml.Node) {
+	doc := root
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		doc = doc.Content[0]
+	}
+	if doc.Line == 0 {
+		doc.Line = 1 // empty file
+	}
+
+	if config.Default == "" {
+		v.add(doc, "default color is required")
+	} else if err := checkColor(config.Default); err != nil {
+		v.add(field(doc, "default"), "invalid default color: %v", err)
+	}
+
+	if config.Generator != "" && !isOneOf(config.Generator, []string{GeneratorShuffle, GeneratorNoise, GeneratorOccurrence}) {
+		v.add(field(doc, "generator"), "invalid generator: %s (must be 'shuffle', 'noise' or 'occurrence')", config.Generator)
+	}
+	noise := field(doc, "noise")
+	if config.Noise.Scale < 0 {
+		v.add(field(noise, "scale"), "invalid noise scale: %g", config.Noise.Scale)
+	}
+	if config.Noise.Octaves < 0 {
+		v.add(field(noise, "octaves"), "invalid noise octaves: %d", config.Noise.Octaves)
+	}
+
+	layout := field(doc, "layout")
+	orientation, offset := config.Layout.Resolve()
+	if offsets, ok := offsetsFor[orientation]; !ok {
+		v.add(field(layout, "orientation"), "invalid hex orientation: %s (must be '%s' or '%s')", orientation, OrientationFlat, OrientationPointy)
+	} else if !isOneOf(offset, offsets) {
+		v.add(field(layout, "offset"), "invalid offset for %s hexes: %s (must be one of %s)", orientation, offset, strings.Join(offsets, ", "))
+	}
+
+	labels := field(doc, "labels")
+	if config.Labels.Format != "" && !isOneOf(config.Labels.Format, LabelFormats) {
+		v.add(field(labels, "format"), "invalid label format: %s (must be one of %s)", config.Labels.Format, strings.Join(LabelFormats, ", "))
+	}
+	if config.Labels.FontSize < 0 || config.Labels.FontSize > 1 {
+		v.add(field(labels, "font_size"), "invalid label font_size: %g (must be between 0 and 1)", config.Labels.FontSize)
+	}
+	if config.Labels.Position != "" && !isOneOf(config.Labels.Position, []string{LabelTop, LabelCenter, LabelBottom}) {
+		v.add(field(labels, "position"), "invalid label position: %s (must be '%s', '%s' or '%s')", config.Labels.Position, LabelTop, LabelCenter, LabelBottom)
+	}
+
+	page := field(doc, "page")
+	_, pageProblems := config.Page.resolve()
+	for _, problem := range pageProblems {
+		v.add(field(page, problem.key), "%s", problem.message)
+	}
+
+	items := field(doc, "items")
+	if len(config.Items) == 0 {
+		v.add(items, "no items defined in configuration")
+		return
+	}
+
+	itemNames := make(map[string]bool)
+	for i, item := range config.Items {
+		if item.Name != "" && itemNames[item.Name] {
+			v.add(field(element(items, i), "name"), "duplicate item name: %s", item.Name)
+		}
+		itemNames[item.Name] = true
+	}
+
+	banded := false
+	totalPercentage, totalChance := 0.0, 0.0
+	for i := range config.Items {
+		item := &config.Items[i]
+		if v.validateItem(item, element(items, i), itemNames) {
+			banded = true
+		}
+		totalPercentage += item.Percentage
+		totalChance += item.Chance
+	}
+
+	if config.Generator == GeneratorNoise && !banded {
+		v.add(field(doc, "generator"), "noise generator requires items with elevation or moisture bands")
+	}
+	v.validateOccurrence(config, doc, items)
+
+	if totalPercentage > MaxTotalPercentage {
+		v.add(items, "total percentage exceeds %d%%: %g", MaxTotalPercentage, totalPercentage)
+	}
+	if totalChance > 100 {
+		v.add(items, "total chance exceeds 100%%: %g", totalChance)
+	}
+}
+
+// validateItem checks a single item type, returning whether it has noise bands
+func (v *validator) validateItem(item *ItemType, node *yaml.Node, itemNames map[string]bool) bool {
+	name := item.Name
+	if name == "" {
+		name = fmt.Sprintf("at line %d", node.Line)
+		v.add(node, "item name is required")
+	}
+
+	if item.Percentage < 0 || item.Percentage > 100 {
+		v.add(field(node, "percentage"), "invalid percentage for item %s: %g", name, item.Percentage)
+	}
+	if item.Chance < 0 || item.Chance > 100 {
+		v.add(field(node, "chance"), "invalid chance for item %s: %g (must be between 0 and 100)", name, item.Chance)
+	}
+	if item.Count != "" {
+		if expr, err := dice.Parse(item.Count); err != nil {
+			v.add(field(node, "count"), "invalid count for item %s: %v", name, err)
+		} else if expr.Min() < 0 {
+			v.add(field(node, "count"), "invalid count for item %s: %s can be negative", name, item.Count)
+		}
+	}
+	var modes []string
+	for _, mode := range []struct {
+		key string
+		set bool
+	}{{"percentage", item.Percentage != 0}, {"count", item.Count != ""}, {"chance", item.Chance != 0}} {
+		if mode.set {
+			modes = append(modes, mode.key)
+		}
+	}
+	if len(modes) > 1 {
+		v.add(field(node, modes[1]), "item %s sets both %s and %s (use one)", name, modes[0], modes[1])
+	}
+	if item.Min < 0 {
+		v.add(field(node, "min"), "invalid min for item %s: %d", name, item.Min)
+	}
+	if item.Max < 0 || (item.Max > 0 && item.Max < item.Min) {
+		v.add(field(node, "max"), "invalid max for item %s: %d (must be at least min)", name, item.Max)
+	}
+	if item.Style != "dot" && item.Style != "fill" {
+		v.add(field(node, "style"), "invalid style for item %s: %s (must be 'dot' or 'fill')", name, item.Style)
+	}
+	if item.Color == "" {
+		v.add(node, "color is required for item %s", name)
+	} else if err := checkColor(item.Color); err != nil {
+		v.add(field(node, "color"), "invalid color for item %s: %v", name, err)
+	}
+	if item.Dice != "" {
+		if _, err := dice.Parse(item.Dice); err != nil {
+			v.add(field(node, "dice"), "invalid dice for item %s: %v", name, err)
+		}
+	}
+	if item.Letter != "" {
+		if n := utf8.RuneCountInString(item.Letter); n > maxLetterLength || strings.IndexFunc(item.Letter, isNotLetterRune) >= 0 {
+			v.add(field(node, "letter"), "invalid letter for item %s: %q (must be 1 to %d visible characters)", name, item.Letter, maxLetterLength)
+		}
+	}
+	if item.Size != "" && !isSize(item.Size) {
+		v.add(field(node, "size"), "invalid size for item %s: %s (must be one of %s)", name, item.Size, strings.Join(Sizes, ", "))
+	}
+
+	if item.Placement != "" && item.Placement != PlacementScatter && item.Placement != PlacementClustered {
+		v.add(field(node, "placement"), "invalid placement for item %s: %s (must be 'scatter' or 'clustered')", name, item.Placement)
+	}
+	if item.ClusterSize < 0 {
+		v.add(field(node, "cluster_size"), "invalid cluster_size for item %s: %d", name, item.ClusterSize)
+	}
+	if item.Cohesion < 0 || item.Cohesion > 1 {
+		v.add(field(node, "cohesion"), "invalid cohesion for item %s: %g (must be between 0 and 1)", name, item.Cohesion)
+	}
+
+	banded := false
+	for _, key := range []string{"elevation", "moisture"} {
+		band := item.Elevation
+		if key == "moisture" {
+			band = item.Moisture
+		}
+		if band == nil {
+			continue
+		}
+		if len(band) != 2 || band[0] < 0 || band[1] > 1 || band[0] > band[1] {
+			v.add(field(node, key), "invalid %s band for item %s: %v (must be [min, max] between 0 and 1)", key, name, []float64(band))
+		}
+		banded = true
+	}
+
+	rules := field(node, "rules")
+	for i, rule := range item.Rules {
+		ruleNode := element(rules, i)
+		if rule.Type != RuleAdjacent && rule.Type != RuleExclude && rule.Type != RuleSurrounded {
+			v.add(field(ruleNode, "type"), "invalid rule type for item %s: %s (must be 'adjacent', 'exclude' or 'surrounded')", name, rule.Type)
+		}
+		if len(rule.Items) == 0 {
+			v.add(ruleNode, "rule %s for item %s has no items", rule.Type, name)
+		}
+		for j, other := range rule.Items {
+			if !itemNames[other] {
+				v.add(element(field(ruleNode, "items"), j), "rule %s for item %s refers to unknown item: %s", rule.Type, name, other)
+			}
+		}
+		if rule.Distance < 0 {
+			v.add(field(ruleNode, "distance"), "invalid rule distance for item %s: %d", name, rule.Distance)
+		}
+	}
+
+	return banded
+}
+
+// validateOccurrence checks the occurrence settings and the weights and
+// rolls of the items, which only the occurrence generator uses
+func (v *validator) validateOccurrence(config *Spec, doc, items *yaml.Node) {
+	occurrence := field(doc, "occurrence")
+	settings := config.Occurrence
+	var table *dice.Expr
+	if config.Generator == GeneratorOccurrence {
+		if settings.Dice == "" {
+			v.add(occurrence, "occurrence generator requires occurrence dice")
+		} else if expr, err := dice.Parse(settings.Dice); err != nil {
+			v.add(field(occurrence, "dice"), "invalid occurrence dice: %v", err)
+		} else if settings.Target > expr.Max() {
+			v.add(field(occurrence, "target"), "invalid occurrence target: %d (%s rolls at most %d)", settings.Target, settings.Dice, expr.Max())
+		}
+		if settings.Table != "" {
+			var err error
+			if table, err = dice.Parse(settings.Table); err != nil {
+				v.add(field(occurrence, "table"), "invalid occurrence table: %v", err)
+			}
+		}
+	}
+
+	placed := false
+	var rolled []*ItemType // Items with a valid roll, to check for overlaps
+	for i := range config.Items {
+		item := &config.Items[i]
+		node := element(items, i)
+		name := item.Name
+		if config.Generator != GeneratorOccurrence {
+			if item.Weight != 0 || item.Roll != nil {
+				v.add(node, "weight and roll of item %s are only used by the occurrence generator", name)
+			}
+			continue
+		}
+
+		for _, key := range []struct {
+			name string
+			set  bool
+		}{
+			{"percentage", item.Percentage != 0}, {"count", item.Count != ""}, {"chance", item.Chance != 0},
+			{"min", item.Min != 0}, {"max", item.Max != 0},
+		} {
+			if key.set {
+				v.add(field(node, key.name), "%s of item %s is not used by the occurrence generator (use weight or roll)", key.name, name)
+			}
+		}
+		if item.Weight < 0 {
+			v.add(field(node, "weight"), "invalid weight for item %s: %g", name, item.Weight)
+		}
+
+		if settings.Table == "" {
+			if item.Roll != nil {
+				v.add(field(node, "roll"), "roll of item %s needs an occurrence table", name)
+			}
+			if item.Weight > 0 {
+				placed = true
+			}
+			continue
+		}
+		if item.Weight != 0 {
+			v.add(field(node, "weight"), "weight of item %s is not used with an occurrence table (use roll)", name)
+		}
+		if item.Roll == nil {
+			continue
+		}
+		placed = true
+		if len(item.Roll) != 2 || item.Roll[0] > item.Roll[1] {
+			v.add(field(node, "roll"), "invalid roll for item %s: %v (must be [min, max])", name, []int(item.Roll))
+			continue
+		}
+		if table != nil && (item.Roll[0] < table.Min() || item.Roll[1] > table.Max()) {
+			v.add(field(node, "roll"), "roll %s of item %s is outside the table (%s rolls %d to %d)", item.Roll, name, settings.Table, table.Min(), table.Max())
+		}
+		for _, other := range rolled {
+			if item.Roll[0] <= other.Roll[1] && other.Roll[0] <= item.Roll[1] {
+				v.add(field(node, "roll"), "roll %s of item %s overlaps item %s", item.Roll, name, other.Name)
+			}
+		}
+		rolled = append(rolled, item)
+	}
+
+	if config.Generator == GeneratorOccurrence && !placed {
+		v.add(field(doc, "generator"), "occurrence generator requires items with a weight, or a roll if there is a table")
+	}
+}
+
+// checkColor reports whether s is a color that ParseColor accepts
+func checkColor(s string) error {
+	_, _, _, err := ParseColor(s)
+	return err
+}
+
+// isSize reports whether s is one of Sizes
+func isSize(s string) bool {
+	return isOneOf(s, Sizes)
+}
+
+// isOneOf reports whether s is in values
+func isOneOf(s string, values []string) bool {
+	for _, value := range values {
+		if s == value {
+			return true
+		}
+	}
+	return false
+}
+
+// isNotLetterRune reports whether r cannot be drawn as part of an item letter
+func isNotLetterRune(r rune) bool {
+	return unicode.IsSpace(r) || !unicode.IsPrint(r)
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"hexgrid/spec"
+)
+
+// defaultSize is the size choice that leaves an item's dot size unset
+const defaultSize = "(default)"
+
+// specEditor edits the items of a spec file: a list of the items beside a
+// form for the selected one, with the running total of the percentages of
+// the items that have one.
+// Edits are made to a spec.Document, so saving keeps the file's comments.
+type specEditor struct {
+	// OnSaved is called after the spec has been saved, if set
+	OnSaved func()
+
+	path     string
+	window   fyne.Window
+	doc      *spec.Document
+	items    []spec.ItemType
+	selected int  // Index of the item in the form, or -1
+	loading  bool // The form is being filled in, so its changes aren't edits
+
+	list         *widget.List
+	name         *widget.Entry
+	quantity     *widget.Select
+	amount       *widget.Entry
+	style        *widget.Select
+	color        *widget.Entry
+	swatch       *canvas.Rectangle
+	pickColorBtn *widget.Button
+	dice         *widget.Entry
+	letter       *widget.Entry
+	size         *widget.Select
+	removeBtn    *widget.Button
+	total        *widget.Label
+
+	content fyne.CanvasObject
+}
+
+// newSpecEditor opens the spec file at path for editing in window
+func newSpecEditor(window fyne.Window, path string) (*specEditor, error) {
+	doc, err := spec.LoadDocument(path)
+	if err != nil {
+		return nil, err
+	}
+	items, err := doc.Items()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &specEditor{path: path, window: window, doc: doc, items: items, selected: -1}
+	e.list = widget.NewList(
+		func() int { return len(e.items) },
+		func() fyne.CanvasObject {
+			swatch := canvas.NewRectangle(color.White)
+			swatch.SetMinSize(fyne.NewSize(16, 16))
+			return container.NewHBox(swatch, widget.NewLabel("Item name"))
+		},
+		func(i widget.ListItemID, object fyne.CanvasObject) {
+			row := object.(*fyne.Container)
+			row.Objects[0].(*canvas.Rectangle).FillColor = itemColor(e.items[i].Color)
+			row.Objects[0].Refresh()
+			row.Objects[1].(*widget.Label).SetText(fmt.Sprintf("%s (%s)", e.items[i].Name, e.items[i].QuantityString()))
+		},
+	)
+	e.list.OnSelected = e.selectItem
+	e.list.OnUnselected = func(widget.ListItemID) { e.selectItem(-1) }
+
+	e.name = widget.NewEntry()
+	e.name.OnChanged = func(value string) {
+		e.edit(func(item *spec.ItemType) { item.Name = value })
+	}
+	e.quantity = widget.NewSelect(spec.Quantities, func(kind string) {
+		e.amount.SetPlaceHolder(amountHints[kind])
+		// The amount may not be one of the new kind, which then starts at zero
+		e.edit(func(item *spec.ItemType) { *item, _ = withQuantity(*item, kind, e.amount.Text) })
+	})
+	e.amount = widget.NewEntry()
+	e.amount.OnChanged = func(value string) {
+		e.edit(func(item *spec.ItemType) {
+			// Wait for an amount while one is being typed
+			if quantity, ok := withQuantity(*item, e.quantity.Selected, value); ok {
+				*item = quantity
+			}
+		})
+	}
+	e.style = widget.NewSelect([]string{"fill", "dot"}, func(value string) {
+		e.edit(func(item *spec.ItemType) { item.Style = value })
+	})
+	e.color = widget.NewEntry()
+	e.color.SetPlaceHolder("#RRGGBB")
+	e.color.OnChanged = func(value string) {
+		e.swatch.FillColor = itemColor(value)
+		e.swatch.Refresh()
+		e.edit(func(item *spec.ItemType) { item.Color = value })
+	}
+	e.swatch = canvas.NewRectangle(color.White)
+	e.swatch.SetMinSize(fyne.NewSize(32, 32))
+	e.pickColorBtn = widget.NewButton("Pick...", e.pickColor)
+	e.dice = widget.NewEntry()
+	e.dice.SetPlaceHolder("none, or e.g. 2d6")
+	e.dice.OnChanged = func(value string) {
+		e.edit(func(item *spec.ItemType) { item.Dice = strings.TrimSpace(value) })
+	}
+	e.letter = widget.NewEntry()
+	e.letter.SetPlaceHolder("none")
+	e.letter.OnChanged = func(value string) {
+		e.edit(func(item *spec.ItemType) { item.Letter = strings.TrimSpace(value) })
+	}
+	e.size = widget.NewSelect(append([]string{defaultSize}, spec.Sizes...), func(value string) {
+		if value == defaultSize {
+			value = ""
+		}
+		e.edit(func(item *spec.ItemType) { item.Size = value })
+	})
+
+	addBtn := widget.NewButton("Add Item", e.addItem)
+	e.removeBtn = widget.NewButton("Remove Item", e.removeItem)
+	saveBtn := widget.NewButton("Save", func() {
+		if err := e.save(); err != nil {
+			dialog.ShowError(err, window)
+		}
+	})
+	e.total = widget.NewLabel("")
+	e.updateTotal()
+	e.selectItem(-1)
+
+	form := widget.NewForm(
+		widget.NewFormItem("Name", e.name),
+		widget.NewFormItem("Quantity", container.NewBorder(nil, nil, e.quantity, nil, e.amount)),
+		widget.NewFormItem("Style", e.style),
+		widget.NewFormItem("Color", container.NewBorder(nil, nil, e.swatch, e.pickColorBtn, e.color)),
+		widget.NewFormItem("Dice", e.dice),
+		widget.NewFormItem("Letter", e.letter),
+		widget.NewFormItem("Size", e.size),
+	)
+	e.content = container.NewBorder(
+		widget.NewLabel("Items of "+filepath.Base(path)),
+		container.NewHBox(addBtn, e.removeBtn, e.total, saveBtn),
+		nil, nil,
+		container.NewHSplit(e.list, container.NewVScroll(form)),
+	)
+	return e, nil
+}
+
+// Content returns the editor's widgets
+func (e *specEditor) Content() fyne.CanvasObject {
+	return e.content
+}
+
+// selectItem fills in the form for the i-th item, or clears and disables it
+// if i is -1
+func (e *specEditor) selectItem(i int) {
+	e.selected = i
+	e.loading = true
+	defer func() { e.loading = false }()
+
+	fields := []fyne.Disableable{e.name, e.quantity, e.amount, e.style, e.color, e.pickColorBtn, e.dice, e.letter, e.size, e.removeBtn}
+	if i < 0 {
+		for _, field := range fields {
+			field.Disable()
+		}
+		for _, entry := range []*widget.Entry{e.name, e.amount, e.color, e.dice, e.letter} {
+			entry.SetText("")
+		}
+		e.quantity.ClearSelected()
+		e.style.ClearSelected()
+		e.size.ClearSelected()
+		return
+	}
+
+	for _, field := range fields {
+		field.Enable()
+	}
+	item := e.items[i]
+	e.name.SetText(item.Name)
+	e.amount.SetText(amountText(item))
+	e.quantity.SetSelected(item.Quantity())
+	e.style.SetSelected(item.Style)
+	e.color.SetText(item.Color)
+	e.dice.SetText(item.Dice)
+	e.letter.SetText(item.Letter)
+	if item.Size == "" {
+		e.size.SetSelected(defaultSize)
+	} else {
+		e.size.SetSelected(item.Size)
+	}
+}
+
+// edit changes the selected item and writes it to the document
+func (e *specEditor) edit(change func(item *spec.ItemType)) {
+	if e.loading || e.selected < 0 {
+		return
+	}
+	change(&e.items[e.selected])
+	if err := e.doc.SetItem(e.selected, e.items[e.selected]); err != nil {
+		dialog.ShowError(err, e.window)
+		return
+	}
+	e.list.RefreshItem(e.selected)
+	e.updateTotal()
+}
+
+// addItem adds a new item and selects it
+func (e *specEditor) addItem() {
+	item := spec.ItemType{Name: fmt.Sprintf("Item %d", len(e.items)+1), Style: "fill", Color: "#808080"}
+	if err := e.doc.AddItem(item); err != nil {
+		dialog.ShowError(err, e.window)
+		return
+	}
+	e.items = append(e.items, item)
+	e.list.Refresh()
+	e.list.Select(len(e.items) - 1)
+	e.updateTotal()
+}
+
+// removeItem removes the selected item
+func (e *specEditor) removeItem() {
+	i := e.selected
+	if i < 0 {
+		return
+	}
+	if err := e.doc.RemoveItem(i); err != nil {
+		dialog.ShowError(err, e.window)
+		return
+	}
+	e.items = append(e.items[:i], e.items[i+1:]...)
+	e.list.UnselectAll()
+	e.selectItem(-1)
+	e.list.Refresh()
+	e.updateTotal()
+}
+
+// pickColor chooses the selected item's color with a color picker
+func (e *specEditor) pickColor() {
+	picker := dialog.NewColorPicker("Item Color", "Choose the color of "+e.name.Text, func(c color.Color) {
+		e.color.SetText(colorHex(c))
+	}, e.window)
+	picker.Advanced = true
+	picker.SetColor(itemColor(e.color.Text))
+	picker.Show()
+}
+
+// updateTotal shows the sum of the percentages of the items that have one
+// against the limit, and how many items have each other kind of quantity
+func (e *specEditor) updateTotal() {
+	total := 0.0
+	others := make(map[string]int)
+	for _, item := range e.items {
+		if quantity := item.Quantity(); quantity == spec.QuantityPercentage {
+			total += item.Percentage
+		} else {
+			others[quantity]++
+		}
+	}
+	text := fmt.Sprintf("Percentage items: %g%% of %d%%", total, spec.MaxTotalPercentage)
+	e.total.Importance = widget.MediumImportance
+	if total > spec.MaxTotalPercentage {
+		text += " (over the limit)"
+		e.total.Importance = widget.DangerImportance
+	}
+	var counts []string
+	for _, quantity := range spec.Quantities {
+		if others[quantity] > 0 {
+			counts = append(counts, fmt.Sprintf("%d by %s", others[quantity], quantity))
+		}
+	}
+	if len(counts) > 0 {
+		text += "; other items: " + strings.Join(counts, ", ")
+	}
+	e.total.SetText(text)
+}
+
+// amountHints are the placeholders of the amount for each kind of quantity
+var amountHints = map[string]string{
+	spec.QuantityPercentage: "% of the grid",
+	spec.QuantityCount:      "hexes, e.g. 1d3",
+	spec.QuantityChance:     "% per hex",
+	spec.QuantityRange:      "hexes, e.g. 1-3 or 2+",
+	spec.QuantityWeight:     "e.g. 2",
+	spec.QuantityRoll:       "table totals, e.g. 7-8",
+}
+
+// amountText formats the amount of an item's quantity for the form
+func amountText(item spec.ItemType) string {
+	switch item.Quantity() {
+	case spec.QuantityCount:
+		return item.Count
+	case spec.QuantityChance:
+		return strconv.FormatFloat(item.Chance, 'f', -1, 64)
+	case spec.QuantityRange:
+		if item.Max == 0 {
+			return fmt.Sprintf("%d+", item.Min)
+		}
+		return spec.RollRange{item.Min, item.Max}.String()
+	case spec.QuantityWeight:
+		return strconv.FormatFloat(item.Weight, 'f', -1, 64)
+	case spec.QuantityRoll:
+		return item.Roll.String()
+	}
+	return strconv.FormatFloat(item.Percentage, 'f', -1, 64)
+}
+
+// withQuantity returns item with its quantity replaced by amount of the given
+// kind, and whether amount is one. If it isn't, the item has none of the kind.
+// The Min and Max of an item they were the range of are cleared, while those
+// that bound a percentage or chance are kept.
+func withQuantity(item spec.ItemType, kind, amount string) (spec.ItemType, bool) {
+	if item.Quantity() == spec.QuantityRange {
+		item.Min, item.Max = 0, 0
+	}
+	item.Percentage, item.Count, item.Chance, item.Weight, item.Roll = 0, "", 0, 0, nil
+	none := item
+
+	amount = strings.TrimSpace(amount)
+	var err error
+	switch kind {
+	case spec.QuantityPercentage:
+		item.Percentage, err = strconv.ParseFloat(amount, 64)
+	case spec.QuantityCount:
+		// Checked as dice when the spec is saved
+		item.Count = amount
+		return item, amount != ""
+	case spec.QuantityChance:
+		item.Chance, err = strconv.ParseFloat(amount, 64)
+	case spec.QuantityRange:
+		if least, ok := strings.CutSuffix(amount, "+"); ok {
+			item.Min, err = parseInt(strings.TrimSpace(least))
+		} else {
+			item.Min, item.Max, err = parseIntRange(amount)
+		}
+	case spec.QuantityWeight:
+		item.Weight, err = strconv.ParseFloat(amount, 64)
+	case spec.QuantityRoll:
+		var low, high int
+		low, high, err = parseIntRange(amount)
+		item.Roll = spec.RollRange{low, high}
+	default:
+		return none, false
+	}
+	if err != nil {
+		return none, false
+	}
+	return item, true
+}
+
+// parseIntRange parses a range of whole numbers written "7-8", or "7" for one.
+// Either end may be negative, as in "-2-1"; the dash that separates them is
+// the first one after the start of the low end.
+func parseIntRange(s string) (int, int, error) {
+	s = strings.TrimSpace(s)
+	split := -1
+	if len(s) > 1 {
+		if i := strings.Index(s[1:], "-"); i >= 0 {
+			split = i + 1
+		}
+	}
+	if split < 0 {
+		low, err := parseInt(s)
+		return low, low, err
+	}
+	low, err := parseInt(s[:split])
+	if err != nil {
+		return 0, 0, err
+	}
+	high, err := parseInt(s[split+1:])
+	return low, high, err
+}
+
+// save writes the spec back to its file, refusing to if it isn't valid
+func (e *specEditor) save() error {
+	data, err := e.doc.Bytes()
+	if err != nil {
+		return err
+	}
+	if _, err := spec.Parse(data); err != nil {
+		return fmt.Errorf("not saved: %w", err)
+	}
+	if err := os.WriteFile(e.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save spec: %w", err)
+	}
+	if e.OnSaved != nil {
+		e.OnSaved()
+	}
+	return nil
+}
+
+// itemColor returns the color of a spec color string, or transparent if it
+// isn't one
+func itemColor(s string) color.Color {
+	r, g, b, err := spec.ParseColor(s)
+	if err != nil {
+		return color.Transparent
+	}
+	return color.NRGBA{R: uint8(r), G: uint8(g), B: uint8(b), A: 0xFF}
+}
+
+// colorHex formats a color as #RRGGBB
+func colorHex(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02X%02X%02X", n.R, n.G, n.B)
+}
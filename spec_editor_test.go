@@ -0,0 +1,191 @@
+package main
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+
+	"hexgrid/spec"
+)
+
+func TestSpecEditor(t *testing.T) {
+	test.NewApp()
+	path := filepath.Join(t.TempDir(), "map.yaml")
+	original := `# Hand-tuned map
+default: "#FFFFFF"
+items:
+  - name: "Forest" # the green bits
+    percentage: 60.0
+    style: "fill"
+    color: "#228B22"
+  - name: "Village"
+    percentage: 10.0
+    style: "dot"
+    color: "#FFD700"
+    dice: "2d6"
+`
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to write spec: %v", err)
+	}
+
+	window := test.NewWindow(nil)
+	defer window.Close()
+	e, err := newSpecEditor(window, path)
+	if err != nil {
+		t.Fatalf("Failed to open spec: %v", err)
+	}
+	saved := false
+	e.OnSaved = func() { saved = true }
+	if e.total.Text != "Percentage items: 70% of 100%" {
+		t.Errorf("Expected a total of 70%%, got %q", e.total.Text)
+	}
+	if !e.name.Disabled() {
+		t.Error("Expected the form disabled with no item selected")
+	}
+
+	// Editing the form changes the selected item and the total
+	e.list.Select(1)
+	if e.name.Text != "Village" || e.dice.Text != "2d6" || e.size.Selected != defaultSize {
+		t.Fatalf("Expected the village in the form, got %q, %q, %q", e.name.Text, e.dice.Text, e.size.Selected)
+	}
+	e.amount.SetText("50")
+	if e.total.Text != "Percentage items: 110% of 100% (over the limit)" {
+		t.Errorf("Expected a total over the limit, got %q", e.total.Text)
+	}
+	if err := e.save(); err == nil || !strings.Contains(err.Error(), "exceeds 100%") {
+		t.Errorf("Expected saving over the limit to fail, got %v", err)
+	}
+	if saved {
+		t.Error("Expected an invalid spec not to be saved")
+	}
+
+	e.amount.SetText("15")
+	e.dice.SetText("")
+	e.size.SetSelected("large")
+	e.addItem()
+	if e.selected != 2 || e.name.Text != "Item 3" {
+		t.Fatalf("Expected the new item selected, got %d, %q", e.selected, e.name.Text)
+	}
+	e.name.SetText("Sea")
+	e.amount.SetText("20")
+	e.color.SetText(colorHex(color.NRGBA{B: 0xFF, A: 0xFF}))
+	if err := e.save(); err != nil {
+		t.Fatalf("Failed to save spec: %v", err)
+	}
+	if !saved {
+		t.Error("Expected OnSaved to be called")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read spec: %v", err)
+	}
+	out := string(data)
+	for _, want := range []string{
+		"# Hand-tuned map\n",
+		`- name: "Forest" # the green bits`,
+		"percentage: 15\n    style: \"dot\"\n    color: \"#FFD700\"\n    size: large\n",
+		"name: Sea\n    percentage: 20\n",
+		"color: '#0000FF'",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected the saved spec to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "2d6") {
+		t.Errorf("Expected the village's dice to be removed, got:\n%s", out)
+	}
+}
+
+func TestSpecEditorQuantity(t *testing.T) {
+	test.NewApp()
+	path := filepath.Join(t.TempDir(), "map.yaml")
+	original := `default: "#FFFFFF"
+items:
+  - name: "Forest"
+    percentage: 60.0
+    style: "fill"
+    color: "#228B22"
+
+  - name: "Village"
+    percentage: 10.0
+    style: "dot"
+    color: "#FFD700"
+`
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to write spec: %v", err)
+	}
+
+	window := test.NewWindow(nil)
+	defer window.Close()
+	e, err := newSpecEditor(window, path)
+	if err != nil {
+		t.Fatalf("Failed to open spec: %v", err)
+	}
+
+	// A village by count leaves the percentage total, and the form keeps
+	// the amount while it's being retyped
+	e.list.Select(1)
+	e.quantity.SetSelected(spec.QuantityCount)
+	e.amount.SetText("1d3")
+	if got := e.total.Text; got != "Percentage items: 60% of 100%; other items: 1 by count" {
+		t.Errorf("Unexpected total %q", got)
+	}
+	if err := e.save(); err != nil {
+		t.Fatalf("Failed to save spec: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read spec: %v", err)
+	}
+	if out := string(data); !strings.Contains(out, "\n\n  - name: \"Village\"\n    count: \"1d3\"\n    style: \"dot\"\n") || strings.Contains(out, "10.0") {
+		t.Errorf("Expected the village to have a count instead of a percentage, got:\n%s", out)
+	}
+
+	e.quantity.SetSelected(spec.QuantityRange)
+	e.amount.SetText("2+")
+	if item := e.items[1]; item.Count != "" || item.Min != 2 || item.Max != 0 {
+		t.Errorf("Expected at least 2 villages, got %+v", item)
+	}
+	e.list.Select(0)
+	e.list.Select(1)
+	if e.quantity.Selected != spec.QuantityRange || e.amount.Text != "2+" {
+		t.Errorf("Expected the range in the form, got %q %q", e.quantity.Selected, e.amount.Text)
+	}
+}
+
+func TestWithQuantity(t *testing.T) {
+	for _, tt := range []struct {
+		item   spec.ItemType
+		kind   string
+		amount string
+		want   spec.ItemType
+		ok     bool
+	}{
+		{spec.ItemType{Percentage: 10}, spec.QuantityChance, "2.5", spec.ItemType{Chance: 2.5}, true},
+		{spec.ItemType{Percentage: 10, Min: 1}, spec.QuantityCount, "2d6", spec.ItemType{Count: "2d6", Min: 1}, true},
+		{spec.ItemType{Min: 1, Max: 3}, spec.QuantityPercentage, "5", spec.ItemType{Percentage: 5}, true},
+		{spec.ItemType{Percentage: 10}, spec.QuantityRange, "1-3", spec.ItemType{Min: 1, Max: 3}, true},
+		{spec.ItemType{Weight: 1}, spec.QuantityRoll, "7-8", spec.ItemType{Roll: spec.RollRange{7, 8}}, true},
+		{spec.ItemType{Weight: 1}, spec.QuantityRoll, "2", spec.ItemType{Roll: spec.RollRange{2, 2}}, true},
+		{spec.ItemType{Percentage: 10}, spec.QuantityRoll, "7-", spec.ItemType{}, false},
+		{spec.ItemType{Weight: 1}, spec.QuantityRoll, "-2-1", spec.ItemType{Roll: spec.RollRange{-2, 1}}, true},
+		{spec.ItemType{Weight: 1}, spec.QuantityRoll, "-3 - -1", spec.ItemType{Roll: spec.RollRange{-3, -1}}, true},
+		{spec.ItemType{Weight: 1}, spec.QuantityRoll, "-3", spec.ItemType{Roll: spec.RollRange{-3, -3}}, true},
+		{spec.ItemType{Weight: 1}, spec.QuantityRoll, "-", spec.ItemType{}, false},
+		{spec.ItemType{Weight: 1}, spec.QuantityRoll, "12abc", spec.ItemType{}, false},
+		{spec.ItemType{Percentage: 10}, spec.QuantityRange, "1-3x", spec.ItemType{}, false},
+		{spec.ItemType{Percentage: 10}, spec.QuantityRange, "2abc+", spec.ItemType{}, false},
+		{spec.ItemType{Count: "1"}, spec.QuantityWeight, "", spec.ItemType{}, false},
+	} {
+		got, ok := withQuantity(tt.item, tt.kind, tt.amount)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v as %s %q: expected %+v, %v, got %+v, %v", tt.item, tt.kind, tt.amount, tt.want, tt.ok, got, ok)
+		}
+	}
+}